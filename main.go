@@ -3,6 +3,7 @@ package main
 import (
 	"os"
 
+	"github.com/oNaiPs/go-generate-fast/src/core/commands"
 	"github.com/oNaiPs/go-generate-fast/src/core/config"
 	"github.com/oNaiPs/go-generate-fast/src/core/generate/base"
 	"github.com/oNaiPs/go-generate-fast/src/core/generate/generate"
@@ -20,7 +21,9 @@ func main() {
 
 	args := os.Args[1:]
 
-	generate.RunGenerate(args)
+	if !commands.Run(args) {
+		generate.RunGenerate(args)
+	}
 
 	zap.S().Debug("End")
 
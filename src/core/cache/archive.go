@@ -0,0 +1,313 @@
+package cache
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/oNaiPs/go-generate-fast/src/core/config"
+	"github.com/oNaiPs/go-generate-fast/src/core/storage"
+	"github.com/oNaiPs/go-generate-fast/src/utils/compress"
+	"github.com/oNaiPs/go-generate-fast/src/utils/flock"
+	"go.uber.org/zap"
+)
+
+const runStampFileName = "run.stamp"
+
+// MarkRunStart records the start of a generate run, so that the entries
+// used by the run can be selected afterwards.
+func MarkRunStart() {
+	err := writeStamp(filepath.Join(config.Get().CacheDir, runStampFileName))
+	if err != nil {
+		zap.S().Debugf("cannot write run stamp: %s", err)
+	}
+}
+
+// LastRunStart returns the start time of the last generate run.
+func LastRunStart() (time.Time, error) {
+	info, err := os.Stat(filepath.Join(config.Get().CacheDir, runStampFileName))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot find last run: %w", err)
+	}
+	return info.ModTime(), nil
+}
+
+type ExportOptions struct {
+	// only export the entries used since then, zero for all entries
+	UsedSince time.Time
+}
+
+type ArchiveResult struct {
+	Entries int
+	Blobs   int
+	// entries that were skipped, when importing
+	Skipped int
+}
+
+// Export writes the selected entries to w as a tar archive, laid out like the
+// cache dir: <key>/cache.json for the entries and blobs/<2 hex chars>/<name>
+// for the blobs they reference.
+func Export(w io.Writer, opts ExportOptions) (ArchiveResult, error) {
+	entries, err := ListEntries()
+	if err != nil {
+		return ArchiveResult{}, err
+	}
+
+	tw := tar.NewWriter(w)
+	result := ArchiveResult{}
+	exportedBlobs := map[string]bool{}
+
+	for _, entry := range entries {
+		if !entry.Complete || entry.LastUsed.Before(opts.UsedSince) {
+			continue
+		}
+
+		n, err := exportEntry(tw, entry, exportedBlobs)
+		if errors.Is(err, errCorruptEntry) {
+			zap.S().Warnf("skipping cache entry %s: %s", entry.Key, err)
+			continue
+		}
+		if err != nil {
+			return result, err
+		}
+
+		result.Entries++
+		result.Blobs += n
+	}
+
+	return result, tw.Close()
+}
+
+// exportEntry writes an entry and its blobs not exported yet, and returns the number of written blobs.
+// Blobs go first, so that importing an entry only requires the files read before it.
+func exportEntry(tw *tar.Writer, entry Entry, exportedBlobs map[string]bool) (int, error) {
+	lock, err := lockEntry(entry.Dir, flock.Shared)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = lock.Release() }()
+
+	configData, err := os.ReadFile(GetConfigFilePath(entry.Dir))
+	if err != nil {
+		return 0, fmt.Errorf("cannot read cache config file: %w: %w", errCorruptEntry, err)
+	}
+
+	var cacheConfig CacheConfig
+	err = json.Unmarshal(configData, &cacheConfig)
+	if err != nil {
+		return 0, fmt.Errorf("cannot unmarshal cache config file: %w: %w", errCorruptEntry, err)
+	}
+
+	n := 0
+	for _, file := range cacheConfig.OutputFiles {
+		name := file.BlobName()
+		if exportedBlobs[name] {
+			continue
+		}
+		if !storage.IsBlob(name) {
+			return n, fmt.Errorf("invalid blob name %q: %w", name, errCorruptEntry)
+		}
+
+		err = addFileToArchive(tw, entryBlobPath(entry.Dir, name), path.Join(storage.BlobsDirName, name[:2], name))
+		if errors.Is(err, os.ErrNotExist) {
+			return n, fmt.Errorf("missing blob of %s: %w", file.Path, errCorruptEntry)
+		}
+		if err != nil {
+			return n, err
+		}
+		exportedBlobs[name] = true
+		n++
+	}
+
+	err = tw.WriteHeader(&tar.Header{
+		Name:    path.Join(entry.Key, configFileName),
+		Mode:    0600,
+		Size:    int64(len(configData)),
+		ModTime: entry.LastUsed,
+	})
+	if err != nil {
+		return n, err
+	}
+	_, err = tw.Write(configData)
+	return n, err
+}
+
+func addFileToArchive(tw *tar.Writer, file string, name string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	err = tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// Import merges the entries of a tar archive written by Export into the cache.
+// Blob hashes are validated, entries with invalid or missing blobs and
+// entries already in the cache are skipped.
+func Import(r io.Reader) (ArchiveResult, error) {
+	tr := tar.NewReader(r)
+	result := ArchiveResult{}
+
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return result, nil
+		}
+		if err != nil {
+			return result, fmt.Errorf("cannot read archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		dir, name := path.Split(header.Name)
+		dir = strings.TrimSuffix(dir, "/")
+
+		switch {
+		case dir == path.Join(storage.BlobsDirName, name[:min(2, len(name))]) && storage.IsBlob(name):
+			err = importBlob(tr, name)
+			if errors.Is(err, errCorruptEntry) {
+				zap.S().Warnf("skipping blob %s: %s", name, err)
+				continue
+			}
+			if err != nil {
+				return result, err
+			}
+			result.Blobs++
+		case name == configFileName && isEntryKey(dir):
+			imported, err := importEntry(tr, dir, header.ModTime)
+			if errors.Is(err, errCorruptEntry) {
+				zap.S().Warnf("skipping cache entry %s: %s", dir, err)
+				result.Skipped++
+				continue
+			}
+			if err != nil {
+				return result, err
+			}
+			if imported {
+				result.Entries++
+			} else {
+				result.Skipped++
+			}
+		default:
+			zap.S().Debugf("ignoring archive file %s", header.Name)
+		}
+	}
+}
+
+// isEntryKey reports whether key is laid out as <1 hex char>/<2 hex chars>/<rest of hash>.
+func isEntryKey(key string) bool {
+	parts := strings.Split(key, "/")
+	return len(parts) == 3 &&
+		len(parts[0]) == 1 && len(parts[1]) == 2 &&
+		isHex(parts[0]) && isHex(parts[1]) && isHex(parts[2])
+}
+
+func importBlob(r io.Reader, name string) error {
+	hash := name[:64]
+	codec := compress.None
+	if strings.HasSuffix(name, compress.Extension(compress.Gzip)) {
+		codec = compress.Gzip
+	}
+
+	tmpFile, err := createBlobTempFile()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmpFile) }()
+
+	f, err := os.OpenFile(tmpFile, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	closeErr := f.Close()
+	if err != nil {
+		return fmt.Errorf("cannot read archive: %w", err)
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+
+	fileHash, err := readBlob(tmpFile, io.Discard, codec)
+	if err != nil {
+		return fmt.Errorf("cannot read blob: %w: %w", errCorruptEntry, err)
+	}
+	if fileHash != hash {
+		return fmt.Errorf("blob hash is different, corruption: %w", errCorruptEntry)
+	}
+
+	return commitBlob(tmpFile, name)
+}
+
+// importEntry commits an entry config read from the archive. Returns false
+// when the entry already exists in the cache.
+func importEntry(r io.Reader, key string, lastUsed time.Time) (bool, error) {
+	cacheHitDir := filepath.Join(config.Get().CacheDir, filepath.FromSlash(key))
+	if isComplete(cacheHitDir) {
+		return false, nil
+	}
+
+	configData, err := io.ReadAll(r)
+	if err != nil {
+		return false, fmt.Errorf("cannot read archive: %w", err)
+	}
+
+	var cacheConfig CacheConfig
+	err = json.Unmarshal(configData, &cacheConfig)
+	if err != nil {
+		return false, fmt.Errorf("cannot unmarshal cache config file: %w: %w", errCorruptEntry, err)
+	}
+
+	for _, file := range cacheConfig.OutputFiles {
+		if !storage.IsBlob(file.BlobName()) || !useBlob(file.BlobName()) {
+			return false, fmt.Errorf("missing blob of %s: %w", file.Path, errCorruptEntry)
+		}
+	}
+
+	stagingDir, err := createStagingDir(cacheHitDir)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = os.RemoveAll(stagingDir) }()
+
+	err = os.WriteFile(GetConfigFilePath(stagingDir), configData, 0600)
+	if err != nil {
+		return false, fmt.Errorf("cannot write cache config file: %w", err)
+	}
+	// keep the last use of the exporting machine, for gc
+	err = os.Chtimes(GetConfigFilePath(stagingDir), lastUsed, lastUsed)
+	if err != nil {
+		return false, fmt.Errorf("cannot write cache config file: %w", err)
+	}
+
+	err = commitEntry(stagingDir, cacheHitDir)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
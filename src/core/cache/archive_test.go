@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/oNaiPs/go-generate-fast/src/core/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportImport(t *testing.T) {
+	setTempCacheDir(t)
+
+	now := time.Now().Truncate(time.Second)
+	entry := saveTestEntry(t, "a/bc/def", 100, now)
+	saveTestEntry(t, "0/12/345", 200, now.Add(-48*time.Hour))
+
+	var archive bytes.Buffer
+	result, err := Export(&archive, ExportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, ArchiveResult{Entries: 2, Blobs: 2}, result)
+
+	var recent bytes.Buffer
+	result, err = Export(&recent, ExportOptions{UsedSince: now.Add(-time.Hour)})
+	require.NoError(t, err)
+	assert.Equal(t, ArchiveResult{Entries: 1, Blobs: 1}, result)
+
+	// import on an empty cache
+	setTempCacheDir(t)
+	result, err = Import(bytes.NewReader(archive.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, ArchiveResult{Entries: 2, Blobs: 2}, result)
+
+	entries, err := ListEntries()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.True(t, entries[0].Complete)
+	assert.Equal(t, now.Add(-48*time.Hour), entries[0].LastUsed)
+
+	require.NoError(t, os.Remove(entry.IoFiles.OutputFiles[0]))
+	entry.CacheHitDir = path.Join(config.Get().CacheDir, entry.CacheKey)
+	restored, err := Restore(entry)
+	require.NoError(t, err)
+	assert.Equal(t, 1, restored)
+	assert.FileExists(t, entry.IoFiles.OutputFiles[0])
+
+	// existing entries are kept
+	result, err = Import(bytes.NewReader(recent.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, ArchiveResult{Entries: 0, Blobs: 1, Skipped: 1}, result)
+}
+
+func TestImportCorruptBlob(t *testing.T) {
+	setTempCacheDir(t)
+
+	entry := saveTestEntry(t, "a/bc/def", 100, time.Now())
+	corruptBlob(t, entry.CacheHitDir)
+
+	// export does not check blob hashes
+	var archive bytes.Buffer
+	_, err := Export(&archive, ExportOptions{})
+	require.NoError(t, err)
+
+	setTempCacheDir(t)
+	result, err := Import(&archive)
+	require.NoError(t, err)
+	assert.Equal(t, ArchiveResult{Skipped: 1}, result)
+
+	entries, err := ListEntries()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestImportIgnoresUnknownFiles(t *testing.T) {
+	setTempCacheDir(t)
+
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	for _, name := range []string{"../escape/cache.json", "x/yz/abc/cache.json", "blobs/zz/foo"} {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: 2}))
+		_, err := tw.Write([]byte("{}"))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+
+	result, err := Import(&archive)
+	require.NoError(t, err)
+	assert.Equal(t, ArchiveResult{}, result)
+
+	entries, err := ListEntries()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
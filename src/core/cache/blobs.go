@@ -0,0 +1,224 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/oNaiPs/go-generate-fast/src/core/config"
+	"github.com/oNaiPs/go-generate-fast/src/core/storage"
+	"github.com/oNaiPs/go-generate-fast/src/utils/compress"
+	"github.com/oNaiPs/go-generate-fast/src/utils/copy"
+	"github.com/oNaiPs/go-generate-fast/src/utils/fs"
+	"go.uber.org/zap"
+)
+
+// blobPath returns the path of a blob on the cache dir.
+func blobPath(name string) string {
+	return storage.BlobPath(config.Get().CacheDir, name)
+}
+
+// entryBlobPath returns the path of an output file blob of an entry.
+// Entries saved before blobs were shared keep them on the entry dir.
+func entryBlobPath(cacheHitDir string, name string) string {
+	legacyPath := filepath.Join(cacheHitDir, name)
+	if _, err := os.Stat(legacyPath); err == nil {
+		return legacyPath
+	}
+	return blobPath(name)
+}
+
+// createBlobTempFile creates an empty temp file on the blobs dir, to be
+// committed as a blob once its hash is known.
+func createBlobTempFile() (string, error) {
+	blobsDir := filepath.Join(config.Get().CacheDir, storage.BlobsDirName)
+	err := fs.MkdirAll(blobsDir, config.Get().DirPerm())
+	if err != nil {
+		return "", fmt.Errorf("cannot create blobs dir: %w", err)
+	}
+
+	f, err := os.CreateTemp(blobsDir, stagingPrefix+"*")
+	if err != nil {
+		return "", fmt.Errorf("cannot create blob temp file: %w", err)
+	}
+	_ = f.Close()
+	return f.Name(), nil
+}
+
+// storeBlob copies a file to the blob store, compressed with the given codec,
+// and returns the hash of its uncompressed content.
+func storeBlob(file string, codec string) (string, error) {
+	tmpFile, err := createBlobTempFile()
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = os.Remove(tmpFile) }()
+
+	hash, err := writeBlob(file, tmpFile, codec)
+	if err != nil {
+		return "", err
+	}
+
+	return hash, commitBlob(tmpFile, hash+compress.Extension(codec))
+}
+
+// commitBlob moves a temp file, whose content matches its hash, to the blob
+// store. A blob already stored by another entry is replaced, so that a
+// corrupt one is not reused.
+func commitBlob(tmpFile string, name string) error {
+	dst := blobPath(name)
+	err := fs.MkdirAll(filepath.Dir(dst), config.Get().DirPerm())
+	if err != nil {
+		return fmt.Errorf("cannot create blobs dir: %w", err)
+	}
+
+	err = os.Chmod(tmpFile, config.Get().FilePerm())
+	if err != nil {
+		return fmt.Errorf("cannot set blob permissions: %w", err)
+	}
+
+	err = os.Rename(tmpFile, dst)
+	// blobs being read cannot be replaced on some platforms
+	if err != nil && !useBlob(name) {
+		return fmt.Errorf("cannot store blob: %w", err)
+	}
+	return nil
+}
+
+// useBlob reports whether the blob is stored, and marks it as used so that
+// a concurrent gc does not sweep it before the entry referencing it is committed.
+func useBlob(name string) bool {
+	return fs.Touch(blobPath(name)) == nil
+}
+
+// writeBlob copies a file to a blob file, compressed with the given codec.
+// Returns the hash of the uncompressed content.
+func writeBlob(src string, dst string, codec string) (string, error) {
+	if codec == compress.None {
+		return copy.CopyHashFile(src, dst)
+	}
+
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = srcFile.Close() }()
+
+	dstFile, err := os.Create(dst)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = dstFile.Close() }()
+
+	w, err := compress.NewWriter(dstFile, codec)
+	if err != nil {
+		return "", err
+	}
+
+	hash, err := copy.CopyHash(w, srcFile)
+	if err != nil {
+		return "", err
+	}
+
+	err = w.Close()
+	if err != nil {
+		return "", err
+	}
+
+	return hash, dstFile.Sync()
+}
+
+// restoreBlob copies a blob to a file, decompressing it with the given codec.
+// Returns the hash of the restored content.
+func restoreBlob(src string, dst string, codec string) (string, error) {
+	if codec == compress.None {
+		return copy.CopyHashFile(src, dst)
+	}
+
+	dstFile, err := os.Create(dst)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = dstFile.Close() }()
+
+	hash, err := readBlob(src, dstFile, codec)
+	if err != nil {
+		return "", err
+	}
+
+	return hash, dstFile.Sync()
+}
+
+// readBlob writes the decompressed content of a blob to w, and returns its hash.
+func readBlob(src string, w io.Writer, codec string) (string, error) {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = srcFile.Close() }()
+
+	r, err := compress.NewReader(srcFile, codec)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = r.Close() }()
+
+	return copy.CopyHash(w, r)
+}
+
+// sweepBlobs removes the blobs that are not referenced by any entry.
+// Blobs used recently are kept, they may belong to entries being saved.
+// Returns the number and size of the removed blobs.
+func sweepBlobs(referenced map[string]bool, dryRun bool) (int, int64, error) {
+	blobsDir := filepath.Join(config.Get().CacheDir, storage.BlobsDirName)
+	if !dryRun {
+		removeStaleStagingDirs(blobsDir)
+	}
+
+	prefixes, err := readEntryDirs(blobsDir, 2)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, 0, nil
+	} else if err != nil {
+		return 0, 0, fmt.Errorf("cannot read blobs dir: %w", err)
+	}
+
+	removed := 0
+	var removedSize int64
+	for _, prefix := range prefixes {
+		dirEntries, err := os.ReadDir(filepath.Join(blobsDir, prefix))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return removed, removedSize, fmt.Errorf("cannot read blobs dir: %w", err)
+		}
+
+		for _, dirEntry := range dirEntries {
+			name := dirEntry.Name()
+			if !storage.IsBlob(name) || !strings.HasPrefix(name, prefix) || referenced[name] {
+				continue
+			}
+
+			info, err := dirEntry.Info()
+			if err != nil || time.Since(info.ModTime()) < staleStagingAge {
+				continue
+			}
+
+			if !dryRun {
+				err = os.Remove(filepath.Join(blobsDir, prefix, name))
+				if err != nil && !errors.Is(err, os.ErrNotExist) {
+					return removed, removedSize, fmt.Errorf("cannot remove blob: %w", err)
+				}
+			}
+			zap.S().Debugf("Removed unreferenced blob %s (%d bytes)", name, info.Size())
+
+			removed++
+			removedSize += info.Size()
+		}
+	}
+
+	return removed, removedSize, nil
+}
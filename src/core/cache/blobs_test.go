@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/oNaiPs/go-generate-fast/src/core/config"
+	"github.com/oNaiPs/go-generate-fast/src/core/storage"
+	"github.com/oNaiPs/go-generate-fast/src/plugins"
+	util_test "github.com/oNaiPs/go-generate-fast/src/test"
+	"github.com/oNaiPs/go-generate-fast/src/utils/compress"
+	"github.com/oNaiPs/go-generate-fast/src/utils/hash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setCompression(t *testing.T, codec string) {
+	t.Helper()
+
+	oldCodec := config.Get().Compression
+	config.Get().Compression = codec
+	t.Cleanup(func() {
+		config.Get().Compression = oldCodec
+	})
+}
+
+func TestCompressedBlobs(t *testing.T) {
+	remoteDir := t.TempDir()
+	setRemoteURL(t, "file://"+remoteDir)
+	setCompression(t, compress.Gzip)
+
+	content := strings.Repeat("package main\n", 1000)
+	output := util_test.WriteTempFile(t, content)
+	key := "a/bc/def"
+	verifyRes := VerifyResult{
+		CacheHitDir: path.Join(config.Get().CacheDir, key),
+		CacheKey:    key,
+		IoFiles: plugins.InputOutputFiles{
+			OutputFiles: []string{output.Name()},
+		},
+	}
+	require.NoError(t, Save(verifyRes))
+
+	cacheConfig, err := LoadConfig(verifyRes.CacheHitDir)
+	require.NoError(t, err)
+	file := cacheConfig.OutputFiles[0]
+	assert.Equal(t, compress.Gzip, file.Compression)
+
+	// the hash is the one of the uncompressed content
+	contentHash, err := hash.HashFile(output.Name())
+	require.NoError(t, err)
+	assert.Equal(t, contentHash, file.Hash)
+
+	info, err := os.Stat(blobPath(file.Hash + ".gz"))
+	require.NoError(t, err)
+	assert.Less(t, info.Size(), int64(len(content)))
+	assert.NoFileExists(t, blobPath(file.Hash))
+	assert.FileExists(t, storage.BlobPath(remoteDir, file.Hash+".gz"))
+
+	// restore from the remote, on an empty local cache
+	assert.NoError(t, os.RemoveAll(verifyRes.CacheHitDir))
+	assert.NoError(t, os.RemoveAll(path.Join(config.Get().CacheDir, storage.BlobsDirName)))
+	assert.NoError(t, os.Remove(output.Name()))
+
+	// entries stay readable when compression is turned off
+	setCompression(t, compress.None)
+
+	verifyRes.RemoteHit = true
+	_, err = Restore(verifyRes)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(output.Name())
+	require.NoError(t, err)
+	assert.Equal(t, content, string(data))
+}
+
+func TestCompressedAndUncompressedBlobs(t *testing.T) {
+	setTempCacheDir(t)
+
+	output := util_test.WriteTempFile(t, "some-content")
+	saveEntry := func(key string) CacheConfigOutputFileInfo {
+		verifyRes := VerifyResult{
+			CacheHitDir: path.Join(config.Get().CacheDir, key),
+			IoFiles:     plugins.InputOutputFiles{OutputFiles: []string{output.Name()}},
+		}
+		require.NoError(t, Save(verifyRes))
+
+		cacheConfig, err := LoadConfig(verifyRes.CacheHitDir)
+		require.NoError(t, err)
+		return cacheConfig.OutputFiles[0]
+	}
+
+	raw := saveEntry("a/bc/aaa")
+	setCompression(t, compress.Gzip)
+	compressed := saveEntry("a/bc/bbb")
+
+	assert.Equal(t, raw.Hash, compressed.Hash)
+	assert.NotEqual(t, raw.BlobName(), compressed.BlobName())
+	assert.FileExists(t, blobPath(raw.BlobName()))
+	assert.FileExists(t, blobPath(compressed.BlobName()))
+}
@@ -1,36 +1,54 @@
 package cache
 
 import (
+	"debug/buildinfo"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"os/exec"
 	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/bmatcuk/doublestar/v4"
 	"github.com/oNaiPs/go-generate-fast/src/core/config"
+	"github.com/oNaiPs/go-generate-fast/src/core/gitindex"
+	"github.com/oNaiPs/go-generate-fast/src/core/goenv"
+	"github.com/oNaiPs/go-generate-fast/src/core/modproxy"
+	"github.com/oNaiPs/go-generate-fast/src/core/statcache"
 	"github.com/oNaiPs/go-generate-fast/src/plugins"
-	"github.com/oNaiPs/go-generate-fast/src/utils/copy"
+	"github.com/oNaiPs/go-generate-fast/src/utils/flock"
 	"github.com/oNaiPs/go-generate-fast/src/utils/fs"
 	"github.com/oNaiPs/go-generate-fast/src/utils/hash"
 	"github.com/oNaiPs/go-generate-fast/src/utils/str"
 	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
 )
 
 type VerifyResult struct {
+	Opts        plugins.GenerateOpts
 	PluginMatch *plugins.Plugin
 	CacheHit    bool
 	CacheHitDir string
-	CanSave     bool
-	IoFiles     plugins.InputOutputFiles
+	// key of the entry on the cache storages, empty when the dir is outside the cache dir
+	CacheKey string
+	// entry is not available locally, but it can be fetched from the remote cache
+	RemoteHit bool
+	CanSave   bool
+	IoFiles   plugins.InputOutputFiles
+	// what the cache key was computed from
+	Manifest KeyManifest
 }
 
 func Verify(opts plugins.GenerateOpts) (VerifyResult, error) {
 	zap.S().Debugf("%s: verifying cache for \"%s\"", opts.Path, opts.Command())
 
-	verifyResult := VerifyResult{}
+	verifyResult := VerifyResult{Opts: opts}
 	var ioFiles *plugins.InputOutputFiles
 
 	plugin := plugins.MatchPlugin(opts)
@@ -44,6 +62,8 @@ func Verify(opts plugins.GenerateOpts) (VerifyResult, error) {
 			zap.S().Debugf("No input output files, skipping cache.")
 			return verifyResult, nil
 		}
+		// entries computed by another version of the plugin may be wrong
+		ioFiles.Extra = append(ioFiles.Extra, "plugin:"+plugins.Fingerprint(plugin))
 	} else {
 		zap.S().Debugf("No plugin was found to handle command.")
 		ioFiles = &plugins.InputOutputFiles{}
@@ -53,35 +73,33 @@ func Verify(opts plugins.GenerateOpts) (VerifyResult, error) {
 		}
 	}
 
-	for _, globPattern := range opts.ExtraInputPatterns {
-		matches, err := doublestar.FilepathGlob(globPattern)
-		if err != nil {
-			zap.S().Error("cannot get extra input files: ", err)
-			continue
-		}
-		ioFiles.InputFiles = append(ioFiles.InputFiles, matches...)
-	}
-
+	// matched on every run, so that removed files are noticed too
+	ioFiles.InputPatterns = append(ioFiles.InputPatterns, opts.ExtraInputPatterns...)
 	ioFiles.OutputPatterns = append(ioFiles.OutputPatterns, opts.ExtraOutputPatterns...)
 
 	str.RemoveDuplicatesAndSort(&ioFiles.InputFiles)
+	str.RemoveDuplicatesAndSort(&ioFiles.InputPatterns)
 	str.RemoveDuplicatesAndSort(&ioFiles.OutputFiles)
 
 	_ = str.ConvertToRelativePaths(&ioFiles.InputFiles, opts.Dir())
 	_ = str.ConvertToRelativePaths(&ioFiles.OutputFiles, opts.Dir())
 
 	zap.S().Debugf("Got %d input files: %s", len(ioFiles.InputFiles), strings.Join(ioFiles.InputFiles, ", "))
+	zap.S().Debugf("Got %d input dirs: %v", len(ioFiles.InputDirs), ioFiles.InputDirs)
+	zap.S().Debugf("Got %d input globs: %s", len(ioFiles.InputPatterns), strings.Join(ioFiles.InputPatterns, ", "))
 	zap.S().Debugf("Got %d output files: %s", len(ioFiles.OutputFiles), strings.Join(ioFiles.OutputFiles, ", "))
 	zap.S().Debugf("Got %d output globs: %s", len(ioFiles.OutputPatterns), strings.Join(ioFiles.OutputPatterns, ", "))
 
-	cacheHitDir, err := calculateCacheDirectoryFromInputData(opts, *ioFiles)
+	cacheHitDir, manifest, err := computeKey(opts, *ioFiles)
 	if err != nil {
 		zap.S().Debugf("Cannot get cache hit dir: %s", err)
 		return verifyResult, err
 	}
 
 	verifyResult.IoFiles = *ioFiles
+	verifyResult.Manifest = manifest
 	verifyResult.CacheHitDir = cacheHitDir
+	verifyResult.CacheKey = cacheKey(cacheHitDir)
 	zap.S().Debugf("Cache hit dir: %s", cacheHitDir)
 
 	fileInfo, err := os.Stat(cacheHitDir)
@@ -93,7 +111,14 @@ func Verify(opts plugins.GenerateOpts) (VerifyResult, error) {
 		return VerifyResult{}, fmt.Errorf("cannot get cache dir info: %w", err)
 	}
 
-	verifyResult.CacheHit = fileInfo != nil && fileInfo.IsDir()
+	verifyResult.CacheHit = fileInfo != nil && fileInfo.IsDir() && isComplete(cacheHitDir)
+	if fileInfo != nil && !verifyResult.CacheHit {
+		removeIncompleteEntry(cacheHitDir)
+	}
+	if !verifyResult.CacheHit {
+		verifyResult.RemoteHit = existsRemote(verifyResult.CacheKey)
+		verifyResult.CacheHit = verifyResult.RemoteHit
+	}
 	verifyResult.CanSave = true
 
 	return verifyResult, nil
@@ -102,7 +127,7 @@ func Verify(opts plugins.GenerateOpts) (VerifyResult, error) {
 func Save(result VerifyResult) error {
 	outputFiles := result.IoFiles.OutputFiles
 	for _, globPattern := range result.IoFiles.OutputPatterns {
-		matches, err := doublestar.FilepathGlob(globPattern, doublestar.WithFilesOnly())
+		matches, err := globFiles(result.Opts.Dir(), globPattern, doublestar.WithFilesOnly())
 		if err != nil {
 			zap.S().Error("cannot extra output files: ", err)
 			continue
@@ -110,59 +135,96 @@ func Save(result VerifyResult) error {
 		outputFiles = append(outputFiles, matches...)
 	}
 
-	err := os.MkdirAll(result.CacheHitDir, 0700)
+	// write the entry on a staging dir first, so that an interrupted save never
+	// leaves behind an entry that looks valid
+	stagingDir, err := createStagingDir(result.CacheHitDir)
 	if err != nil {
-		return fmt.Errorf("cannot create cache dir: %w", err)
+		return err
+	}
+	defer func() { _ = os.RemoveAll(stagingDir) }()
+
+	cacheConfig := CacheConfig{
+		CreatedAt: time.Now(),
+		// expanded words may hold the values of environment variables
+		Command:  result.Opts.Directive,
+		File:     result.Opts.Path,
+		Line:     result.Opts.Line,
+		Manifest: result.Manifest,
+	}
+	if result.PluginMatch != nil {
+		cacheConfig.Plugin = (*result.PluginMatch).Name()
 	}
 
-	cacheConfig := CacheConfig{}
-
-	//use an intermediary file since we don't know the file hash until we finish copying it
-	tmpFile := path.Join(result.CacheHitDir, "file.swp")
-
+	codec := config.Get().Compression
 	for _, file := range outputFiles {
-		if err != nil {
-			return fmt.Errorf("cannot create temp dir: %w", err)
-		}
-
-		hash, err := copy.CopyHashFile(file, tmpFile)
+		// blobs are shared by all entries, identical outputs are only stored once
+		hash, err := storeBlob(fs.ResolvePath(result.Opts.Dir(), file), codec)
 		if err != nil {
 			return fmt.Errorf("cannot copy file to cache: %w", err)
 		}
 
-		err = os.Rename(tmpFile, path.Join(result.CacheHitDir, hash))
-		if err != nil {
-			return fmt.Errorf("rename file to be cached: %w", err)
-		}
-
-		fileStat, err := os.Stat(file)
+		fileStat, err := os.Stat(fs.ResolvePath(result.Opts.Dir(), file))
 		if err != nil {
 			return fmt.Errorf("cannot stat cached file: %w", err)
 		}
 
 		cacheConfig.OutputFiles = append(cacheConfig.OutputFiles, CacheConfigOutputFileInfo{
-			Hash:    hash,
-			Path:    file,
-			ModTime: fileStat.ModTime(),
+			Hash:        hash,
+			Path:        file,
+			ModTime:     fileStat.ModTime(),
+			Compression: codec,
 		})
 	}
 
-	err = SaveConfig(cacheConfig, result.CacheHitDir)
+	err = SaveConfig(cacheConfig, stagingDir)
 	if err != nil {
 		return fmt.Errorf("cannot write cache config: %w", err)
 	}
 
+	err = commitEntry(stagingDir, result.CacheHitDir)
+	if err != nil {
+		return err
+	}
+
+	removeStaleStagingDirs(filepath.Dir(result.CacheHitDir))
+
 	zap.S().Debug("Saved cache on ", result.CacheHitDir)
 
+	uploadRemote(result.CacheKey, result.CacheHitDir, cacheConfig)
+
 	return nil
 }
 
-func Restore(result VerifyResult) error {
+// Restore copies the cached output files to their destination, and returns
+// the number of files that were copied, skipping the ones already up to date.
+func Restore(result VerifyResult) (int, error) {
 	zap.S().Debugf("Restoring cache")
 
+	if result.RemoteHit {
+		err := fetchRemote(result.CacheKey, result.CacheHitDir)
+		if err != nil {
+			return 0, fmt.Errorf("cannot fetch remote cache: %w", err)
+		}
+	}
+
+	restored, err := restore(result)
+	if errors.Is(err, errCorruptEntry) {
+		quarantineEntry(result.CacheHitDir)
+	}
+	return restored, err
+}
+
+func restore(result VerifyResult) (int, error) {
+	// prevent the entry from being replaced or removed while it is read
+	lock, err := lockEntry(result.CacheHitDir, flock.Shared)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = lock.Release() }()
+
 	cacheConfig, err := LoadConfig(result.CacheHitDir)
 	if err != nil {
-		return fmt.Errorf("cannot read cache config: %w", err)
+		return 0, fmt.Errorf("cannot read cache config: %w: %w", errCorruptEntry, err)
 	}
 
 	// confirm that the expected output files match the ones in the saved cache config
@@ -170,11 +232,14 @@ func Restore(result VerifyResult) error {
 	// TODO: check if the non-matching output files match the provided glob
 	if len(result.IoFiles.OutputPatterns) == 0 &&
 		!areOutputsMatching(cacheConfig.OutputFiles, result.IoFiles.OutputFiles) {
-		return errors.New("expected output files differ")
+		return 0, errors.New("expected output files differ")
 	}
 
+	restored := 0
 	for _, dstFile := range cacheConfig.OutputFiles {
-		srcFile := path.Join(result.CacheHitDir, dstFile.Hash)
+		srcFile := entryBlobPath(result.CacheHitDir, dstFile.BlobName())
+		// saved relative to the command dir
+		dstFile.Path = fs.ResolvePath(result.Opts.Dir(), dstFile.Path)
 
 		// skip if modification time is the same
 		dstFileStat, err := os.Stat(dstFile.Path)
@@ -185,25 +250,67 @@ func Restore(result VerifyResult) error {
 
 		err = os.MkdirAll(path.Dir(dstFile.Path), 0755)
 		if err != nil {
-			return fmt.Errorf("cannot create destination directory: %w", err)
+			return restored, fmt.Errorf("cannot create destination directory: %w", err)
 		}
 
-		hash, err := copy.CopyHashFile(srcFile, dstFile.Path)
+		err = restoreFile(srcFile, dstFile)
 		if err != nil {
-			return fmt.Errorf("cannot copy file from cache: %w", err)
+			return restored, err
 		}
+		restored++
 		zap.S().Debug("Copied file from cache: ", dstFile.Path)
+	}
+
+	err = touchEntry(result.CacheHitDir)
+	if err != nil {
+		zap.S().Debugf("cannot update cache entry last use: %s", err)
+	}
+	err = markRestored(result.CacheHitDir)
+	if err != nil {
+		zap.S().Debugf("cannot mark cache entry as restored: %s", err)
+	}
 
-		err = os.Chtimes(dstFile.Path, dstFile.ModTime, dstFile.ModTime)
+	return restored, nil
+}
+
+// restoreFile restores a blob through a temp file, so that the destination
+// file is only replaced once the restored content is verified.
+func restoreFile(srcFile string, dstFile CacheConfigOutputFileInfo) error {
+	_, err := os.Stat(srcFile)
+	if err != nil {
+		return fmt.Errorf("cannot copy file from cache: %w: %w", errCorruptEntry, err)
+	}
+
+	dir, name := filepath.Split(dstFile.Path)
+	tmpFile := filepath.Join(dir, fmt.Sprintf(".%s.%d.tmp", name, time.Now().UnixNano()))
+	defer func() { _ = os.Remove(tmpFile) }()
+
+	hash, err := restoreBlob(srcFile, tmpFile, dstFile.Compression)
+	if err != nil {
+		return fmt.Errorf("cannot copy file from cache: %w", err)
+	}
+
+	if hash != dstFile.Hash {
+		return fmt.Errorf("file hash is different, corruption: %w", errCorruptEntry)
+	}
+
+	// keep the permissions of the file being replaced
+	if info, err := os.Stat(dstFile.Path); err == nil {
+		err = os.Chmod(tmpFile, info.Mode().Perm())
 		if err != nil {
-			return fmt.Errorf("cannot restore times for destination file: %w", err)
+			return fmt.Errorf("cannot restore permissions for destination file: %w", err)
 		}
+	}
 
-		if hash != dstFile.Hash {
-			return errors.New("file hash is different, corruption")
-		}
+	err = os.Chtimes(tmpFile, dstFile.ModTime, dstFile.ModTime)
+	if err != nil {
+		return fmt.Errorf("cannot restore times for destination file: %w", err)
 	}
 
+	err = os.Rename(tmpFile, dstFile.Path)
+	if err != nil {
+		return fmt.Errorf("cannot copy file from cache: %w", err)
+	}
 	return nil
 }
 
@@ -225,42 +332,119 @@ func areOutputsMatching(outputFiles []CacheConfigOutputFileInfo, resultFiles []s
 }
 
 func calculateCacheDirectoryFromInputData(opts plugins.GenerateOpts, ioFiles plugins.InputOutputFiles) (string, error) {
-	contentToHash :=
-		opts.Dir() +
-			strings.Join(opts.Words, "\n") +
-			strings.Join(ioFiles.InputFiles, "\n") +
-			strings.Join(ioFiles.OutputFiles, "\n") +
-			strings.Join(ioFiles.OutputPatterns, "\n") +
-			strings.Join(ioFiles.Extra, "\n")
-
-	for _, file := range ioFiles.InputFiles {
-		hash, err := hash.HashFile(file)
-		if err != nil {
-			return "", fmt.Errorf("cannot hash file '%s': %w", file, err)
-		}
-		contentToHash += hash
+	cacheHitDir, _, err := computeKey(opts, ioFiles)
+	return cacheHitDir, err
+}
+
+// computeKey returns the cache dir of a directive, and the manifest of the
+// components its key is computed from.
+func computeKey(opts plugins.GenerateOpts, ioFiles plugins.InputOutputFiles) (string, KeyManifest, error) {
+	// paths are relative to the key root, so that keys do not depend on the
+	// location of the checkout
+	root := keyRoot(opts)
+	dir, _ := keyPath(opts, root, ".")
+
+	m := KeyManifest{
+		{Kind: "version", Value: keyVersion},
+		{Kind: "salt", Value: config.Get().CacheSalt},
+		{Kind: "dir", Value: dir},
+	}
+	// words as written, the manifest is uploaded with the entry. The values of
+	// the variables they reference are in the hashed env components.
+	words := opts.Words
+	if opts.Directive != "" {
+		words = strings.Fields(opts.Directive)
+	}
+	for i, word := range relocateAll(root, words) {
+		m.add("arg", strconv.Itoa(i), word)
+	}
+	for _, file := range keyOutputFiles(opts, root, ioFiles.OutputFiles) {
+		m.add("output", file, "")
+	}
+	for _, pattern := range relocateAll(root, ioFiles.OutputPatterns) {
+		m.add("output-pattern", pattern, "")
+	}
+	for _, extra := range relocateAll(root, ioFiles.Extra) {
+		// e.g. plugin:<name>@<version>
+		name, value, _ := strings.Cut(extra, ":")
+		m.add("extra", name, value)
 	}
 
-	if opts.GoPackage == "" {
-		execInfo, err := getExecutableDetails(opts.ExecutableName)
+	inputFiles, err := keyInputFiles(opts, root, ioFiles.InputFiles)
+	if err != nil {
+		return "", nil, err
+	}
+	m = append(m, inputFiles...)
+
+	inputDirs, err := keyInputDirs(opts, root, ioFiles.InputDirs)
+	if err != nil {
+		return "", nil, err
+	}
+	m = append(m, inputDirs...)
+
+	inputPatterns, err := keyInputPatterns(opts, root, ioFiles.InputPatterns)
+	if err != nil {
+		return "", nil, err
+	}
+	m = append(m, inputPatterns...)
+
+	envHashes, err := hashEnv(opts.Env)
+	if err != nil {
+		return "", nil, err
+	}
+	m = append(m, envHashes...)
+
+	if opts.GoTool != "" {
+		toolInfo, err := goToolInfo(opts)
 		if err != nil {
-			return "", fmt.Errorf("cannot get path for executable '%s': %s", opts.ExecutableName, err)
+			return "", nil, fmt.Errorf("cannot identify tool '%s': %w", opts.GoTool, err)
 		}
-		contentToHash += execInfo
+		m = append(m, toolInfo...)
+	} else if opts.GoPackage == "" {
+		execInfo, err := getExecutableDetails(opts.Dir(), opts.ExecutableName)
+		if err != nil {
+			return "", nil, fmt.Errorf("cannot get path for executable '%s': %s", opts.ExecutableName, err)
+		}
+		m.add("executable", opts.ExecutableName, execInfo)
 	} else {
-		// we can only hash specific versions/hashes
-		if opts.GoPackageVersion != "" && opts.GoPackageVersion != "latest" {
-			hash, err := hash.HashString(opts.GoPackage + "/" + opts.GoPackageVersion)
+		// generated code often depends on the toolchain go run builds with
+		env, err := goenv.Get(opts.Dir())
+		if err != nil {
+			return "", nil, err
+		}
+		m.add("go", "", env.GOVERSION)
+
+		// generators without version are identified by what they are built from
+		if opts.GoPackageVersion == "" {
+			deps, err := goRunDeps(opts)
+			if err != nil {
+				return "", nil, err
+			}
+			sourceFiles, err := keyInputFiles(opts, root, deps.Files)
+			if err != nil {
+				return "", nil, err
+			}
+			m = append(m, sourceFiles...)
+			m = append(m, keyModules(deps.Modules)...)
+		}
+
+		version := opts.GoPackageVersion
+		if version == "latest" {
+			modPath, latest, err := modproxy.ResolveLatest(opts.Dir(), opts.GoPackage)
 			if err != nil {
-				return "", fmt.Errorf("cannot hash string: %w", err)
+				return "", nil, fmt.Errorf("cannot resolve latest version of %s: %w", opts.GoPackage, err)
 			}
-			contentToHash += hash
+			version = modPath + "@" + latest
+		}
+
+		if version != "" {
+			m.add("package", opts.GoPackage, version)
 		}
 	}
 
-	finalHash, err := hash.HashString(contentToHash)
+	finalHash, err := m.hash()
 	if err != nil {
-		return "", fmt.Errorf("cannot get final hash: %s", err)
+		return "", nil, fmt.Errorf("cannot get final hash: %s", err)
 	}
 
 	cacheHitDir := path.Join(
@@ -269,15 +453,146 @@ func calculateCacheDirectoryFromInputData(opts plugins.GenerateOpts, ioFiles plu
 		finalHash[1:3],
 		finalHash[3:])
 
-	return cacheHitDir, nil
+	return cacheHitDir, m, nil
+}
+
+// hashEnv returns a component per environment variable with the hash of its
+// value, sorted by name, so that values do not end up in plaintext on the
+// cache.
+func hashEnv(env map[string]string) (KeyManifest, error) {
+	var m KeyManifest
+	for _, name := range slices.Sorted(maps.Keys(env)) {
+		h, err := hash.HashString(env[name])
+		if err != nil {
+			return nil, fmt.Errorf("cannot hash string: %w", err)
+		}
+		m.add("env", name, h)
+	}
+	return m, nil
+}
+
+// hashInputFiles returns the hashes of the input files, in order. Files are
+// hashed concurrently, by a bounded number of workers. In git hash mode, files
+// are identified by their git blob IDs instead, taken from the index for the
+// clean tracked ones. Outside of a repository, they are all hashed by content.
+func hashInputFiles(opts plugins.GenerateOpts, files []string) ([]string, error) {
+	blobIDs := map[string]string{}
+	hashFile := statcache.HashFile
+	if config.Get().HashMode == config.HashModeGit {
+		var objectFormat string
+		blobIDs, objectFormat = gitindex.BlobIDs(opts.Dir(), files)
+		if objectFormat != "" {
+			hashFile = func(file string) (string, error) {
+				return gitindex.HashFile(file, objectFormat)
+			}
+		}
+	}
+
+	hashes := make([]string, len(files))
+	var g errgroup.Group
+	g.SetLimit(runtime.GOMAXPROCS(0))
+
+	for i, file := range files {
+		if id, ok := blobIDs[file]; ok {
+			hashes[i] = id
+			continue
+		}
+
+		g.Go(func() error {
+			hash, err := hashFile(fs.ResolvePath(opts.Dir(), file))
+			if err != nil {
+				return fmt.Errorf("cannot hash file '%s': %w", file, err)
+			}
+			hashes[i] = hash
+			return nil
+		})
+	}
+
+	return hashes, g.Wait()
 }
 
-func resolveExecutablePath(executable string) (string, error) {
+// globFiles returns the files matching a pattern. Relative patterns are
+// matched from dir, and so are the returned files.
+func globFiles(dir string, pattern string, opts ...doublestar.GlobOption) ([]string, error) {
+	if filepath.IsAbs(pattern) {
+		return doublestar.FilepathGlob(pattern, opts...)
+	}
+
+	matches, err := doublestar.FilepathGlob(filepath.Join(dir, pattern), opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, match := range matches {
+		matches[i], err = filepath.Rel(dir, match)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return matches, nil
+}
+
+// listInputDir returns the files of an input directory, sorted, with paths
+// relative to the dir. Excluded directories are not walked. A missing
+// directory has no files.
+func listInputDir(opts plugins.GenerateOpts, inputDir plugins.InputDir) ([]string, error) {
+	root := fs.ResolvePath(opts.Dir(), inputDir.Path)
+
+	files := []string{}
+	err := filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if errors.Is(err, os.ErrNotExist) && p == root {
+			return filepath.SkipAll
+		}
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if matchesAny(inputDir.Exclude, rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if len(inputDir.Include) == 0 || matchesAny(inputDir.Include, rel) {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot list input dir '%s': %w", inputDir.Path, err)
+	}
+
+	slices.Sort(files)
+	return files, nil
+}
+
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := doublestar.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func resolveExecutablePath(dir string, executable string) (string, error) {
 	// Support `go tool <exe>` by resolving the real tool path via `go tool -n`
 	const goToolPrefix = "go tool "
 	if strings.HasPrefix(executable, goToolPrefix) {
 		tool := strings.TrimPrefix(executable, goToolPrefix)
-		out, err := exec.Command("go", "tool", "-n", tool).CombinedOutput()
+		cmd := exec.Command("go", "tool", "-n", tool)
+		// tools are resolved from the module of the command dir
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
 		if err != nil {
 			return "", fmt.Errorf("cannot resolve %q via 'go tool -n': %w (output: %s)", executable, err, string(out))
 		}
@@ -289,8 +604,8 @@ func resolveExecutablePath(executable string) (string, error) {
 	return fs.FindExecutablePath(executable)
 }
 
-func getExecutableDetails(ExecutablePath string) (string, error) {
-	ExecutablePath, err := resolveExecutablePath(ExecutablePath)
+func getExecutableDetails(dir string, ExecutablePath string) (string, error) {
+	ExecutablePath, err := resolveExecutablePath(dir, ExecutablePath)
 	if err != nil {
 		return "", err
 	}
@@ -300,6 +615,26 @@ func getExecutableDetails(ExecutablePath string) (string, error) {
 		return "", err
 	}
 
+	switch config.Get().ExecFingerprint {
+	case config.ExecFingerprintBuildInfo:
+		execInfo, err := getExecutableBuildInfo(ExecutablePath)
+		if err == nil {
+			zap.S().Debugf("Exec info %s", execInfo)
+			return execInfo, nil
+		}
+		zap.S().Debugf("Cannot identify %s by build info, hashing it instead: %s", ExecutablePath, err)
+		fallthrough
+	case config.ExecFingerprintContent:
+		// the path is left out, so that the same executable installed
+		// elsewhere keeps the same key
+		hash, err := statcache.HashFile(ExecutablePath)
+		if err != nil {
+			return "", fmt.Errorf("cannot hash executable: %w", err)
+		}
+		zap.S().Debugf("Exec info %s", hash)
+		return "content:" + hash, nil
+	}
+
 	execInfo := fmt.Sprint(
 		ExecutablePath,
 		fmt.Sprintf("%019d", info.Size()),
@@ -309,3 +644,21 @@ func getExecutableDetails(ExecutablePath string) (string, error) {
 
 	return execInfo, nil
 }
+
+// getExecutableBuildInfo returns the main module path and version of a go
+// executable. Fails when it is not a go executable, or it was not built from
+// a released module version.
+func getExecutableBuildInfo(executablePath string) (string, error) {
+	info, err := buildinfo.ReadFile(executablePath)
+	if err != nil {
+		return "", err
+	}
+
+	version := info.Main.Version
+	// local builds report (devel), or a +dirty version with uncommitted changes
+	if version == "" || version == "(devel)" || strings.HasSuffix(version, "+dirty") {
+		return "", fmt.Errorf("no released version of module %q", info.Main.Path)
+	}
+
+	return fmt.Sprintf("buildinfo:%s@%s:%s", info.Path, version, info.GoVersion), nil
+}
@@ -1,7 +1,9 @@
 package cache
 
 import (
+	"fmt"
 	"os"
+	"os/exec"
 	"path"
 	"plugin"
 	"strings"
@@ -13,6 +15,7 @@ import (
 	util_test "github.com/oNaiPs/go-generate-fast/src/test"
 	"github.com/oNaiPs/go-generate-fast/src/utils/hash"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestMain(m *testing.M) {
@@ -21,6 +24,16 @@ func TestMain(m *testing.M) {
 	os.Exit(code)
 }
 
+func setTempCacheDir(t *testing.T) {
+	t.Helper()
+
+	oldCacheDir := config.Get().CacheDir
+	config.Get().CacheDir = t.TempDir()
+	t.Cleanup(func() {
+		config.Get().CacheDir = oldCacheDir
+	})
+}
+
 type TestPlugin struct {
 	plugin.Plugin
 	t *testing.T
@@ -30,6 +43,10 @@ func (p *TestPlugin) Name() string {
 	return "test"
 }
 
+func (p *TestPlugin) Version() string {
+	return "1"
+}
+
 func (p *TestPlugin) Matches(opts plugins.GenerateOpts) bool {
 	return opts.ExecutableName == "test"
 }
@@ -79,6 +96,9 @@ func TestCacheVerify(t *testing.T) {
 }
 
 func newVerifyResult(t *testing.T) VerifyResult {
+	// blobs are stored on the cache dir
+	setTempCacheDir(t)
+
 	file1 := util_test.WriteTempFile(t, "some-content")
 	file2 := util_test.WriteTempFile(t, "some-other-content")
 
@@ -129,8 +149,9 @@ func TestRestore(t *testing.T) {
 	err := Save(verifyRes)
 	assert.NoError(t, err)
 
-	err = Restore(verifyRes)
+	restored, err := Restore(verifyRes)
 	assert.NoError(t, err)
+	assert.Equal(t, 0, restored, "outputs are up to date")
 
 	//test for file corruption
 	file1 := util_test.WriteTempFile(t, "some-content")
@@ -162,7 +183,7 @@ func TestRestore(t *testing.T) {
 
 	assert.NoError(t, err)
 
-	err = Restore(verifyRes)
+	_, err = Restore(verifyRes)
 	assert.ErrorContains(t, err, "file hash is different, corruption")
 }
 
@@ -216,6 +237,27 @@ func TestCalculateCacheDirectoryFromInputData(t *testing.T) {
 	dir4, err := calculateCacheDirectoryFromInputData(opts, ioFiles)
 	assert.NoError(t, err)
 	assert.NotEqual(t, dir3, dir4, "Different command should produce different cache directory")
+
+	// Test 5: Different environment should produce different cache directory
+	opts.Env = map[string]string{"GOOS": "linux"}
+	dir5, err := calculateCacheDirectoryFromInputData(opts, ioFiles)
+	assert.NoError(t, err)
+	assert.NotEqual(t, dir4, dir5, "Different environment should produce different cache directory")
+
+	opts.Env = map[string]string{"GOOS": "windows"}
+	dir6, err := calculateCacheDirectoryFromInputData(opts, ioFiles)
+	assert.NoError(t, err)
+	assert.NotEqual(t, dir5, dir6, "Different environment value should produce different cache directory")
+
+	// Test 6: Different cache salt should produce different cache directory
+	oldSalt := config.Get().CacheSalt
+	config.Get().CacheSalt = "bad-release"
+	t.Cleanup(func() {
+		config.Get().CacheSalt = oldSalt
+	})
+	dir7, err := calculateCacheDirectoryFromInputData(opts, ioFiles)
+	assert.NoError(t, err)
+	assert.NotEqual(t, dir6, dir7, "Different cache salt should produce different cache directory")
 }
 
 func TestExecutableFileInfo(t *testing.T) {
@@ -225,18 +267,220 @@ func TestExecutableFileInfo(t *testing.T) {
 	err := os.Chmod(tmpFile.Name(), 0700)
 	assert.Nil(t, err, "Failed to chmod file")
 
-	info, err := getExecutableDetails(tmpFile.Name())
+	info, err := getExecutableDetails("", tmpFile.Name())
 
 	assert.NoError(t, err)
 	assert.Equal(t, info, tmpFile.Name()+"00000000000000000111990-01-01T00:00:00Z")
 
-	_, err = getExecutableDetails("bad_file")
+	_, err = getExecutableDetails("", "bad_file")
 	assert.ErrorContains(t, err, "executable file not found in $PATH")
 }
 
 
+func setExecFingerprint(t *testing.T, fingerprint string) {
+	t.Helper()
+
+	old := config.Get().ExecFingerprint
+	config.Get().ExecFingerprint = fingerprint
+	t.Cleanup(func() {
+		config.Get().ExecFingerprint = old
+	})
+}
+
+func TestExecutableFileInfoContent(t *testing.T) {
+	setExecFingerprint(t, config.ExecFingerprintContent)
+
+	// same executable installed on two places, at different times
+	file1 := path.Join(t.TempDir(), "tool")
+	file2 := path.Join(t.TempDir(), "tool")
+	require.NoError(t, os.WriteFile(file1, []byte("tool v1"), 0700))
+	require.NoError(t, os.WriteFile(file2, []byte("tool v1"), 0700))
+	require.NoError(t, os.Chtimes(file2, time.Unix(0, 0), time.Unix(0, 0)))
+
+	info1, err := getExecutableDetails("", file1)
+	assert.NoError(t, err)
+	info2, err := getExecutableDetails("", file2)
+	assert.NoError(t, err)
+	assert.Equal(t, info1, info2)
+
+	// rebuilt with the same size and modification time
+	require.NoError(t, os.WriteFile(file2, []byte("tool v2"), 0700))
+	require.NoError(t, os.Chtimes(file2, time.Unix(0, 0), time.Unix(0, 0)))
+	info2, err = getExecutableDetails("", file2)
+	assert.NoError(t, err)
+	assert.NotEqual(t, info1, info2)
+}
+
+func TestExecutableFileInfoBuildInfo(t *testing.T) {
+	setExecFingerprint(t, config.ExecFingerprintBuildInfo)
+
+	// not a go executable, identified by content
+	file := path.Join(t.TempDir(), "tool")
+	require.NoError(t, os.WriteFile(file, []byte("tool v1"), 0700))
+	info, err := getExecutableDetails("", file)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(info, "content:"), info)
+
+	mockgen, err := exec.LookPath("mockgen")
+	if err != nil {
+		t.Skip("mockgen not installed")
+	}
+	info, err = getExecutableDetails("", mockgen)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(info, "buildinfo:go.uber.org/mock/mockgen@"), info)
+}
+
 func TestExecutableFileInfoGoTool(t *testing.T) {
-	info, err := getExecutableDetails("go tool compile")
+	info, err := getExecutableDetails("", "go tool compile")
 	assert.NoError(t, err)
 	assert.NotEmpty(t, info)
 }
+
+func TestVerifyRelativeToCommandDir(t *testing.T) {
+	setTempCacheDir(t)
+
+	// files are resolved from the command dir, not from the working dir
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(dir, "a.in"), []byte("a"), 0600))
+	require.NoError(t, os.WriteFile(path.Join(dir, "b.in"), []byte("b"), 0600))
+	require.NoError(t, os.MkdirAll(path.Join(dir, "out"), 0700))
+	require.NoError(t, os.WriteFile(path.Join(dir, "out", "gen.txt"), []byte("generated"), 0600))
+
+	opts := plugins.GenerateOpts{
+		Path:                path.Join(dir, "gen.go"),
+		Words:               []string{"go", "version"},
+		ExecutableName:      "go",
+		ExtraInputPatterns:  []string{"*.in"},
+		ExtraOutputPatterns: []string{"out/*.txt"},
+	}
+
+	verifyRes, err := Verify(opts)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"*.in"}, verifyRes.IoFiles.InputPatterns)
+	require.NoError(t, Save(verifyRes))
+
+	cacheConfig, err := LoadConfig(verifyRes.CacheHitDir)
+	require.NoError(t, err)
+	require.Len(t, cacheConfig.OutputFiles, 1)
+	assert.Equal(t, path.Join("out", "gen.txt"), cacheConfig.OutputFiles[0].Path)
+
+	require.NoError(t, os.RemoveAll(path.Join(dir, "out")))
+
+	verifyRes, err = Verify(opts)
+	require.NoError(t, err)
+	assert.True(t, verifyRes.CacheHit)
+
+	restored, err := Restore(verifyRes)
+	require.NoError(t, err)
+	assert.Equal(t, 1, restored)
+	data, err := os.ReadFile(path.Join(dir, "out", "gen.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "generated", string(data))
+
+	// a changed input changes the entry
+	require.NoError(t, os.WriteFile(path.Join(dir, "b.in"), []byte("changed"), 0600))
+	changedRes, err := Verify(opts)
+	require.NoError(t, err)
+	assert.NotEqual(t, verifyRes.CacheHitDir, changedRes.CacheHitDir)
+	assert.False(t, changedRes.CacheHit)
+}
+
+type versionedPlugin struct {
+	plugin.Plugin
+	version string
+}
+
+func (p *versionedPlugin) Name() string {
+	return "versioned"
+}
+
+func (p *versionedPlugin) Version() string {
+	return p.version
+}
+
+func (p *versionedPlugin) Matches(opts plugins.GenerateOpts) bool {
+	return opts.ExecutableName == "go"
+}
+
+func (p *versionedPlugin) ComputeInputOutputFiles(opts plugins.GenerateOpts) *plugins.InputOutputFiles {
+	return &plugins.InputOutputFiles{}
+}
+
+func TestVerifyPluginVersion(t *testing.T) {
+	setTempCacheDir(t)
+
+	plugins.ClearPlugins()
+	t.Cleanup(plugins.ClearPlugins)
+	testPlugin := versionedPlugin{version: "1"}
+	plugins.RegisterPlugin(&testPlugin)
+
+	opts := plugins.GenerateOpts{
+		Path:           path.Join(t.TempDir(), "gen.go"),
+		Words:          []string{"go", "version"},
+		ExecutableName: "go",
+	}
+
+	verifyRes, err := Verify(opts)
+	require.NoError(t, err)
+	verifyRes2, err := Verify(opts)
+	require.NoError(t, err)
+	assert.Equal(t, verifyRes.CacheHitDir, verifyRes2.CacheHitDir)
+
+	// entries of other versions of the plugin are not reused
+	testPlugin.version = "2"
+	verifyRes2, err = Verify(opts)
+	require.NoError(t, err)
+	assert.NotEqual(t, verifyRes.CacheHitDir, verifyRes2.CacheHitDir)
+}
+
+func TestListInputDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.txt", "a.txt", "sub/c.txt", "sub/d.bin", "vendor/e.txt"} {
+		require.NoError(t, os.MkdirAll(path.Dir(path.Join(dir, name)), 0700))
+		require.NoError(t, os.WriteFile(path.Join(dir, name), []byte(name), 0600))
+	}
+	opts := plugins.GenerateOpts{Path: path.Join(dir, "gen.go")}
+
+	files, err := listInputDir(opts, plugins.InputDir{Path: "."})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.txt", "b.txt", "sub/c.txt", "sub/d.bin", "vendor/e.txt"}, files)
+
+	files, err = listInputDir(opts, plugins.InputDir{
+		Path:    ".",
+		Include: []string{"**/*.txt"},
+		Exclude: []string{"vendor", "b.*"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.txt", "sub/c.txt"}, files)
+
+	files, err = listInputDir(opts, plugins.InputDir{Path: "sub"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c.txt", "d.bin"}, files)
+
+	files, err = listInputDir(opts, plugins.InputDir{Path: "missing"})
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestHashInputFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{}
+	for i := range 20 {
+		name := fmt.Sprintf("file%d", i)
+		require.NoError(t, os.WriteFile(path.Join(dir, name), []byte(name), 0600))
+		files = append(files, name)
+	}
+
+	opts := plugins.GenerateOpts{Path: path.Join(dir, "gen.go")}
+	hashes, err := hashInputFiles(opts, files)
+	require.NoError(t, err)
+	require.Len(t, hashes, len(files))
+	for i, file := range files {
+		expected, err := hash.HashFile(path.Join(dir, file))
+		require.NoError(t, err)
+		assert.Equal(t, expected, hashes[i], "hashes shall keep the order of the files")
+	}
+
+	_, err = hashInputFiles(opts, append(files, "missing"))
+	assert.ErrorContains(t, err, "cannot hash file 'missing'")
+}
@@ -0,0 +1,201 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/oNaiPs/go-generate-fast/src/core/config"
+	"github.com/oNaiPs/go-generate-fast/src/utils/flock"
+	"github.com/oNaiPs/go-generate-fast/src/utils/fs"
+	"go.uber.org/zap"
+)
+
+const (
+	quarantineDirName = "quarantine"
+	// quarantined entries are kept this long for inspection, then removed by gc
+	quarantineAge = 7 * 24 * time.Hour
+)
+
+// errCorruptEntry is wrapped by the errors caused by entries whose config or
+// blobs are missing or do not match.
+var errCorruptEntry = errors.New("corrupt cache entry")
+
+type CorruptEntry struct {
+	Entry
+	Error error
+	// the entry was removed from the cache
+	Removed bool
+}
+
+type CheckResult struct {
+	Checked int
+	Corrupt []CorruptEntry
+}
+
+// CheckEntries re-hashes the blobs of every entry and compares them with the
+// hashes of its config. When remove is set, corrupt entries are removed.
+func CheckEntries(remove bool) (CheckResult, error) {
+	entries, err := ListEntries()
+	if err != nil {
+		return CheckResult{}, err
+	}
+
+	result := CheckResult{}
+	for _, entry := range entries {
+		result.Checked++
+
+		err := checkEntry(entry)
+		if err == nil {
+			continue
+		}
+		zap.S().Debugf("Corrupt cache entry %s: %s", entry.Key, err)
+
+		corrupt := CorruptEntry{Entry: entry, Error: err}
+		if remove {
+			blobs := corruptBlobs(entry.Dir)
+			err = removeEntry(entry)
+			if err != nil && !errors.Is(err, flock.ErrLocked) {
+				return result, err
+			}
+			corrupt.Removed = err == nil
+			if corrupt.Removed {
+				for _, blob := range blobs {
+					_ = os.Remove(blob)
+				}
+			}
+		}
+		result.Corrupt = append(result.Corrupt, corrupt)
+	}
+
+	return result, nil
+}
+
+func checkEntry(entry Entry) error {
+	lock, err := lockEntry(entry.Dir, flock.Shared)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = lock.Release() }()
+
+	if !isComplete(entry.Dir) {
+		return errors.New("incomplete entry")
+	}
+
+	cacheConfig, err := LoadConfig(entry.Dir)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range cacheConfig.OutputFiles {
+		hash, err := readBlob(entryBlobPath(entry.Dir, file.BlobName()), io.Discard, file.Compression)
+		if err != nil {
+			return fmt.Errorf("cannot read blob of %s: %w", file.Path, err)
+		}
+		if hash != file.Hash {
+			return fmt.Errorf("blob of %s hash is different, corruption", file.Path)
+		}
+	}
+
+	return nil
+}
+
+// corruptBlobs returns the paths of the shared blobs of an entry whose
+// content does not match their hash. They must go along with the corrupt
+// entry, or new entries with the same outputs would reference them again.
+func corruptBlobs(cacheHitDir string) []string {
+	cacheConfig, err := LoadConfig(cacheHitDir)
+	if err != nil {
+		return nil
+	}
+
+	var paths []string
+	for _, file := range cacheConfig.OutputFiles {
+		p := entryBlobPath(cacheHitDir, file.BlobName())
+		// blobs on the entry dir go with it
+		if p != blobPath(file.BlobName()) || slices.Contains(paths, p) {
+			continue
+		}
+
+		hash, err := readBlob(p, io.Discard, file.Compression)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil || hash != file.Hash {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// quarantineEntry moves a corrupt entry out of the cache, so that it is not
+// hit again. It is kept for a while for inspection.
+func quarantineEntry(cacheHitDir string) {
+	key := cacheKey(cacheHitDir)
+	if key == "" {
+		return
+	}
+
+	lock, err := tryLockEntry(cacheHitDir, flock.Exclusive)
+	if err != nil {
+		zap.S().Debugf("cannot lock corrupt cache entry: %s", err)
+		return
+	}
+	defer func() { _ = lock.Release() }()
+
+	quarantineDir := filepath.Join(config.Get().CacheDir, quarantineDirName)
+	err = fs.MkdirAll(quarantineDir, config.Get().DirPerm())
+	if err != nil {
+		zap.S().Warnf("cannot quarantine cache entry: %s", err)
+		return
+	}
+
+	blobs := corruptBlobs(cacheHitDir)
+
+	dst := filepath.Join(quarantineDir, strings.ReplaceAll(key, "/", ""))
+	_ = os.RemoveAll(dst)
+	err = os.Rename(cacheHitDir, dst)
+	if err != nil {
+		zap.S().Warnf("cannot quarantine cache entry: %s", err)
+		return
+	}
+
+	// kept on the quarantined entry dir, like blobs of entries saved before
+	// blobs were shared
+	for _, blob := range blobs {
+		err = os.Rename(blob, filepath.Join(dst, filepath.Base(blob)))
+		if err != nil {
+			zap.S().Debugf("cannot quarantine corrupt blob: %s", err)
+			_ = os.Remove(blob)
+		}
+	}
+	// the quarantine time, removed by gc once expired
+	_ = fs.Touch(dst)
+
+	zap.S().Warnf("Quarantined corrupt cache entry %s to %s", key, dst)
+}
+
+// removeExpiredQuarantine removes the quarantined entries kept for longer than quarantineAge.
+func removeExpiredQuarantine() {
+	quarantineDir := filepath.Join(config.Get().CacheDir, quarantineDirName)
+	dirEntries, err := os.ReadDir(quarantineDir)
+	if err != nil {
+		return
+	}
+
+	for _, dirEntry := range dirEntries {
+		info, err := dirEntry.Info()
+		if err != nil || time.Since(info.ModTime()) < quarantineAge {
+			continue
+		}
+
+		dir := filepath.Join(quarantineDir, dirEntry.Name())
+		zap.S().Debugf("Removing quarantined cache entry %s", dir)
+		_ = os.RemoveAll(dir)
+	}
+}
@@ -0,0 +1,163 @@
+package cache
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/oNaiPs/go-generate-fast/src/core/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// corruptBlob overwrites the blob of the first output file of an entry.
+func corruptBlob(t *testing.T, cacheHitDir string) {
+	t.Helper()
+
+	cacheConfig, err := LoadConfig(cacheHitDir)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(blobPath(cacheConfig.OutputFiles[0].BlobName()), []byte("corrupted"), 0600))
+}
+
+func TestCheckEntries(t *testing.T) {
+	setTempCacheDir(t)
+
+	now := time.Now()
+	saveTestEntry(t, "a/bc/aaa", 10, now)
+	corrupt := saveTestEntry(t, "a/bc/bbb", 10, now)
+	broken := saveTestEntry(t, "a/bc/ccc", 10, now)
+
+	corruptBlob(t, corrupt.CacheHitDir)
+	require.NoError(t, os.WriteFile(GetConfigFilePath(broken.CacheHitDir), []byte("{"), 0600))
+
+	result, err := CheckEntries(false)
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Checked)
+	require.Len(t, result.Corrupt, 2)
+	assert.Equal(t, "a/bc/bbb", result.Corrupt[0].Key)
+	assert.ErrorContains(t, result.Corrupt[0].Error, "hash is different, corruption")
+	assert.False(t, result.Corrupt[0].Removed)
+	assert.Equal(t, "a/bc/ccc", result.Corrupt[1].Key)
+	assert.ErrorContains(t, result.Corrupt[1].Error, "cannot unmarshal cache config file")
+	assert.DirExists(t, corrupt.CacheHitDir)
+
+	result, err = CheckEntries(true)
+	require.NoError(t, err)
+	require.Len(t, result.Corrupt, 2)
+	assert.True(t, result.Corrupt[0].Removed)
+	assert.NoDirExists(t, corrupt.CacheHitDir)
+	assert.NoDirExists(t, broken.CacheHitDir)
+
+	result, err = CheckEntries(false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Checked)
+	assert.Empty(t, result.Corrupt)
+}
+
+func TestRestoreQuarantinesCorruptEntry(t *testing.T) {
+	setTempCacheDir(t)
+
+	verifyRes := saveTestEntry(t, "a/bc/def", 10, time.Now())
+	corruptBlob(t, verifyRes.CacheHitDir)
+
+	output := verifyRes.IoFiles.OutputFiles[0]
+	require.NoError(t, os.WriteFile(output, []byte("user-content"), 0600))
+
+	_, err := Restore(verifyRes)
+	assert.ErrorContains(t, err, "file hash is different, corruption")
+
+	data, err := os.ReadFile(output)
+	require.NoError(t, err)
+	assert.Equal(t, "user-content", string(data), "output shall not be overwritten by corrupt content")
+
+	assert.NoDirExists(t, verifyRes.CacheHitDir, "corrupt entry shall not be hit again")
+	quarantined := path.Join(config.Get().CacheDir, quarantineDirName, "abcdef")
+	assert.DirExists(t, quarantined)
+
+	entries, err := ListEntries()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// kept for inspection, until expired
+	_, err = GC(GCOptions{})
+	require.NoError(t, err)
+	assert.DirExists(t, quarantined)
+
+	past := time.Now().Add(-2 * quarantineAge)
+	require.NoError(t, os.Chtimes(quarantined, past, past))
+	_, err = GC(GCOptions{})
+	require.NoError(t, err)
+	assert.NoDirExists(t, quarantined)
+}
+
+func TestRestoreMissingConfigQuarantines(t *testing.T) {
+	setTempCacheDir(t)
+
+	verifyRes := saveTestEntry(t, "a/bc/def", 10, time.Now())
+	require.NoError(t, os.Remove(GetConfigFilePath(verifyRes.CacheHitDir)))
+
+	_, err := Restore(verifyRes)
+	assert.ErrorContains(t, err, "cannot read cache config")
+	assert.NoDirExists(t, verifyRes.CacheHitDir)
+}
+
+func TestQuarantineRemovesCorruptBlob(t *testing.T) {
+	setTempCacheDir(t)
+
+	verifyRes := saveTestEntry(t, "a/bc/def", 10, time.Now())
+	cacheConfig, err := LoadConfig(verifyRes.CacheHitDir)
+	require.NoError(t, err)
+	blob := blobPath(cacheConfig.OutputFiles[0].BlobName())
+	corruptBlob(t, verifyRes.CacheHitDir)
+
+	output := verifyRes.IoFiles.OutputFiles[0]
+	content, err := os.ReadFile(output)
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(output))
+
+	_, err = Restore(verifyRes)
+	assert.ErrorContains(t, err, "file hash is different, corruption")
+	assert.NoFileExists(t, blob, "corrupt blob shall not be reused")
+	assert.FileExists(t, path.Join(config.Get().CacheDir, quarantineDirName, "abcdef", path.Base(blob)))
+
+	// generated and saved again by the next run, with the same outputs
+	require.NoError(t, os.WriteFile(output, content, 0600))
+	require.NoError(t, Save(verifyRes))
+
+	require.NoError(t, os.Remove(output))
+	restored, err := Restore(verifyRes)
+	require.NoError(t, err)
+	assert.Equal(t, 1, restored)
+}
+
+func TestCommitBlobReplacesCorruptBlob(t *testing.T) {
+	setTempCacheDir(t)
+
+	verifyRes := saveTestEntry(t, "a/bc/def", 10, time.Now())
+	corruptBlob(t, verifyRes.CacheHitDir)
+
+	// an entry with the same outputs, e.g. under a corrupt entry left by an
+	// older version
+	require.NoError(t, Save(verifyRes))
+	require.NoError(t, os.Remove(verifyRes.IoFiles.OutputFiles[0]))
+	restored, err := Restore(verifyRes)
+	require.NoError(t, err)
+	assert.Equal(t, 1, restored)
+}
+
+func TestCheckEntriesRemovesCorruptBlob(t *testing.T) {
+	setTempCacheDir(t)
+
+	verifyRes := saveTestEntry(t, "a/bc/def", 10, time.Now())
+	cacheConfig, err := LoadConfig(verifyRes.CacheHitDir)
+	require.NoError(t, err)
+	blob := blobPath(cacheConfig.OutputFiles[0].BlobName())
+	corruptBlob(t, verifyRes.CacheHitDir)
+
+	result, err := CheckEntries(true)
+	require.NoError(t, err)
+	require.Len(t, result.Corrupt, 1)
+	assert.True(t, result.Corrupt[0].Removed)
+	assert.NoFileExists(t, blob)
+}
@@ -0,0 +1,172 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/oNaiPs/go-generate-fast/src/core/config"
+	"github.com/oNaiPs/go-generate-fast/src/core/storage"
+	"github.com/oNaiPs/go-generate-fast/src/utils/flock"
+	"github.com/oNaiPs/go-generate-fast/src/utils/fs"
+	"go.uber.org/zap"
+)
+
+const (
+	// written last in an entry, entries without it are leftovers of interrupted saves
+	completeFileName = storage.CompleteFileName
+	// touched on every restore of an entry, missing when never restored
+	restoredFileName = "restored"
+	// prefix of the staging dirs, created next to the entry they will become
+	stagingPrefix = ".tmp-"
+	// staging dirs older than this are considered abandoned
+	staleStagingAge = time.Hour
+)
+
+// createStagingDir creates a temporary dir where an entry is written before
+// being committed. It is created on the same parent dir as the entry, so that
+// committing it is a single rename.
+func createStagingDir(cacheHitDir string) (string, error) {
+	parentDir := filepath.Dir(cacheHitDir)
+	perm := config.Get().DirPerm()
+
+	var stagingDir string
+	var err error
+	// the parent dir can be removed by a concurrent gc once it is empty
+	for range 3 {
+		err = fs.MkdirAll(parentDir, perm)
+		if err != nil {
+			return "", fmt.Errorf("cannot create cache dir: %w", err)
+		}
+
+		stagingDir, err = os.MkdirTemp(parentDir, stagingPrefix+filepath.Base(cacheHitDir)+"-")
+		if !errors.Is(err, os.ErrNotExist) {
+			break
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("cannot create staging dir: %w", err)
+	}
+
+	err = os.Chmod(stagingDir, perm)
+	if err != nil {
+		_ = os.Remove(stagingDir)
+		return "", fmt.Errorf("cannot create staging dir: %w", err)
+	}
+	return stagingDir, nil
+}
+
+// commitEntry marks a staging dir as complete and atomically moves it to the entry dir,
+// replacing any existing entry.
+func commitEntry(stagingDir string, cacheHitDir string) error {
+	err := os.WriteFile(filepath.Join(stagingDir, completeFileName), []byte{}, 0600)
+	if err != nil {
+		return fmt.Errorf("cannot write complete marker: %w", err)
+	}
+
+	err = setFilesPerm(stagingDir)
+	if err != nil {
+		return err
+	}
+
+	// wait for processes still reading an entry that is about to be replaced
+	lock, err := lockEntry(cacheHitDir, flock.Exclusive)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = lock.Release() }()
+
+	err = os.Rename(stagingDir, cacheHitDir)
+	if err == nil {
+		return nil
+	}
+
+	// the entry dir already exists and is not empty, move it out of the way first
+	oldDir, err := os.MkdirTemp(filepath.Dir(cacheHitDir), stagingPrefix+filepath.Base(cacheHitDir)+"-old-")
+	if err != nil {
+		return fmt.Errorf("cannot create staging dir: %w", err)
+	}
+	err = os.Rename(cacheHitDir, filepath.Join(oldDir, "entry"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		_ = os.Remove(oldDir)
+		return fmt.Errorf("cannot replace existing entry: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(oldDir) }()
+
+	err = os.Rename(stagingDir, cacheHitDir)
+	if err != nil {
+		return fmt.Errorf("cannot commit entry: %w", err)
+	}
+	return nil
+}
+
+// setFilesPerm sets the configured permissions on the files of a staging dir,
+// which were created with the permissions allowed by the umask.
+func setFilesPerm(stagingDir string) error {
+	dirEntries, err := os.ReadDir(stagingDir)
+	if err != nil {
+		return fmt.Errorf("cannot read staging dir: %w", err)
+	}
+
+	for _, dirEntry := range dirEntries {
+		err = os.Chmod(filepath.Join(stagingDir, dirEntry.Name()), config.Get().FilePerm())
+		if err != nil {
+			return fmt.Errorf("cannot set cache file permissions: %w", err)
+		}
+	}
+	return nil
+}
+
+// isComplete reports whether the entry was fully written.
+func isComplete(cacheHitDir string) bool {
+	_, err := os.Stat(filepath.Join(cacheHitDir, completeFileName))
+	return err == nil
+}
+
+// removeIncompleteEntry removes an entry left behind without its complete
+// marker, unless another process is using it.
+func removeIncompleteEntry(cacheHitDir string) {
+	lock, err := tryLockEntry(cacheHitDir, flock.Exclusive)
+	if err != nil {
+		zap.S().Debugf("cannot lock incomplete cache entry: %s", err)
+		return
+	}
+	defer func() { _ = lock.Release() }()
+
+	// committed by another process in the meantime
+	if isComplete(cacheHitDir) {
+		return
+	}
+
+	zap.S().Debugf("Removing incomplete cache entry: %s", cacheHitDir)
+	err = os.RemoveAll(cacheHitDir)
+	if err != nil {
+		zap.S().Warnf("cannot remove incomplete cache entry: %s", err)
+	}
+}
+
+// removeStaleStagingDirs removes abandoned staging dirs from an entry parent dir,
+// left behind by processes that were interrupted while saving.
+func removeStaleStagingDirs(parentDir string) {
+	dirEntries, err := os.ReadDir(parentDir)
+	if err != nil {
+		return
+	}
+
+	for _, dirEntry := range dirEntries {
+		if !strings.HasPrefix(dirEntry.Name(), stagingPrefix) {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil || time.Since(info.ModTime()) < staleStagingAge {
+			continue
+		}
+
+		stagingDir := filepath.Join(parentDir, dirEntry.Name())
+		zap.S().Debugf("Removing stale staging dir %s", stagingDir)
+		_ = os.RemoveAll(stagingDir)
+	}
+}
@@ -0,0 +1,126 @@
+package cache
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/oNaiPs/go-generate-fast/src/core/config"
+	"github.com/oNaiPs/go-generate-fast/src/plugins"
+	util_test "github.com/oNaiPs/go-generate-fast/src/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveIsCommittedAtomically(t *testing.T) {
+	verifyRes := newVerifyResult(t)
+	parentDir := filepath.Dir(verifyRes.CacheHitDir)
+
+	err := Save(verifyRes)
+	assert.NoError(t, err)
+	assert.True(t, isComplete(verifyRes.CacheHitDir))
+	assert.NoFileExists(t, path.Join(verifyRes.CacheHitDir, "file.swp"))
+
+	matches, err := filepath.Glob(path.Join(parentDir, stagingPrefix+"*"))
+	assert.NoError(t, err)
+	assert.Empty(t, matches, "no staging dirs shall be left behind")
+
+	// saving again replaces the existing entry
+	err = os.WriteFile(verifyRes.IoFiles.OutputFiles[0], []byte("new-content"), 0600)
+	assert.NoError(t, err)
+	err = Save(verifyRes)
+	assert.NoError(t, err)
+
+	cacheConfig, err := LoadConfig(verifyRes.CacheHitDir)
+	assert.NoError(t, err)
+	assert.FileExists(t, blobPath(cacheConfig.OutputFiles[0].Hash))
+
+	matches, err = filepath.Glob(path.Join(parentDir, stagingPrefix+"*"))
+	assert.NoError(t, err)
+	assert.Empty(t, matches)
+}
+
+func TestSaveFailureLeavesNoEntry(t *testing.T) {
+	setTempCacheDir(t)
+
+	cacheHitDir := path.Join(t.TempDir(), "entry")
+	verifyRes := VerifyResult{
+		CacheHitDir: cacheHitDir,
+		IoFiles: plugins.InputOutputFiles{
+			OutputFiles: []string{path.Join(t.TempDir(), "missing.go")},
+		},
+	}
+
+	err := Save(verifyRes)
+	assert.ErrorContains(t, err, "cannot copy file to cache")
+	assert.NoDirExists(t, cacheHitDir)
+
+	matches, err := filepath.Glob(path.Join(filepath.Dir(cacheHitDir), stagingPrefix+"*"))
+	assert.NoError(t, err)
+	assert.Empty(t, matches)
+}
+
+func TestVerifyIgnoresIncompleteEntries(t *testing.T) {
+	plugins.ClearPlugins()
+	setTempCacheDir(t)
+
+	input := util_test.WriteTempFile(t, "input")
+	t.Chdir(filepath.Dir(input.Name()))
+	opts := plugins.GenerateOpts{
+		Path:                path.Join(filepath.Dir(input.Name()), "test.go"),
+		Words:               []string{"go", "version"},
+		ExecutableName:      "go",
+		ExtraInputPatterns:  []string{input.Name()},
+		ExtraOutputPatterns: []string{"out.txt"},
+	}
+
+	result, err := Verify(opts)
+	assert.NoError(t, err)
+	assert.False(t, result.CacheHit)
+
+	// simulate an interrupted save from an older version, without the complete marker
+	assert.NoError(t, os.MkdirAll(result.CacheHitDir, 0700))
+	assert.NoError(t, SaveConfig(CacheConfig{}, result.CacheHitDir))
+
+	result, err = Verify(opts)
+	assert.NoError(t, err)
+	assert.False(t, result.CacheHit)
+	assert.NoDirExists(t, result.CacheHitDir, "incomplete entry shall be removed")
+
+	assert.NoError(t, os.MkdirAll(result.CacheHitDir, 0700))
+	assert.NoError(t, SaveConfig(CacheConfig{}, result.CacheHitDir))
+	assert.NoError(t, os.WriteFile(path.Join(result.CacheHitDir, completeFileName), []byte{}, 0600))
+
+	result, err = Verify(opts)
+	assert.NoError(t, err)
+	assert.True(t, result.CacheHit)
+}
+
+func TestGCRemovesLeftovers(t *testing.T) {
+	setTempCacheDir(t)
+
+	old := time.Now().Add(-2 * staleStagingAge)
+	parentDir := path.Join(config.Get().CacheDir, "a", "bc")
+
+	staleStagingDir := path.Join(parentDir, stagingPrefix+"ddd-123")
+	freshStagingDir := path.Join(parentDir, stagingPrefix+"eee-456")
+	incompleteEntry := path.Join(parentDir, "fff")
+	for _, dir := range []string{staleStagingDir, freshStagingDir, incompleteEntry} {
+		assert.NoError(t, os.MkdirAll(dir, 0700))
+	}
+	assert.NoError(t, os.Chtimes(staleStagingDir, old, old))
+	assert.NoError(t, os.Chtimes(incompleteEntry, old, old))
+
+	saveTestEntry(t, "a/bc/aaa", 10, time.Now())
+
+	result, err := GC(GCOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Removed)
+	assert.Equal(t, 1, result.Remaining)
+
+	assert.NoDirExists(t, staleStagingDir)
+	assert.NoDirExists(t, incompleteEntry)
+	assert.DirExists(t, freshStagingDir, "staging dirs of running saves shall be kept")
+	assert.DirExists(t, path.Join(parentDir, "aaa"))
+}
@@ -6,20 +6,46 @@ import (
 	"os"
 	"path"
 	"time"
+
+	"github.com/oNaiPs/go-generate-fast/src/core/storage"
+	"github.com/oNaiPs/go-generate-fast/src/utils/compress"
 )
 
+const configFileName = storage.ConfigFileName
+
 type CacheConfigOutputFileInfo struct {
+	// hash of the uncompressed content
 	Hash    string
 	Path    string
 	ModTime time.Time
+	// codec of the blob, empty when stored uncompressed
+	Compression string `json:",omitempty"`
+}
+
+// BlobName returns the name of the blob holding the file content.
+func (f CacheConfigOutputFileInfo) BlobName() string {
+	return f.Hash + compress.Extension(f.Compression)
 }
 
 type CacheConfig struct {
 	OutputFiles []CacheConfigOutputFileInfo
+	// when the entry was saved
+	CreatedAt time.Time
+	// name of the plugin that computed the inputs and outputs, empty for custom commands
+	Plugin string
+	// generate command that created the entry, as written in the file
+	Command string
+	// file containing the generate directive
+	File string
+	// line of the directive in the file
+	Line int `json:",omitempty"`
+	// what the cache key was computed from, missing on entries saved by older
+	// versions
+	Manifest KeyManifest `json:",omitempty"`
 }
 
 func GetConfigFilePath(cacheHitDir string) string {
-	return path.Join(cacheHitDir, "cache.json")
+	return path.Join(cacheHitDir, configFileName)
 }
 
 func SaveConfig(config CacheConfig, cacheHitDir string) error {
@@ -38,6 +64,11 @@ func SaveConfig(config CacheConfig, cacheHitDir string) error {
 	if err != nil {
 		return fmt.Errorf("cannot write cache config file: %w", err)
 	}
+
+	err = file.Sync()
+	if err != nil {
+		return fmt.Errorf("cannot write cache config file: %w", err)
+	}
 	return nil
 }
 
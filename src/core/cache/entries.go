@@ -0,0 +1,230 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/oNaiPs/go-generate-fast/src/core/config"
+	"github.com/oNaiPs/go-generate-fast/src/core/storage"
+	"github.com/oNaiPs/go-generate-fast/src/utils/flock"
+	"github.com/oNaiPs/go-generate-fast/src/utils/fs"
+)
+
+// Entry is a cache entry found on the cache dir.
+type Entry struct {
+	Key string
+	Dir string
+	// total size of the entry files, including the shared blobs it references
+	Size int64
+	// sizes of the shared blobs referenced by the entry, by blob name
+	Blobs map[string]int64
+	// last time the entry was saved or restored
+	LastUsed time.Time
+	// last time the entry was restored, zero when never restored
+	LastRestored time.Time
+	// entry was fully written
+	Complete bool
+}
+
+// BlobSet returns the names of the shared blobs referenced by the entry.
+func (e Entry) BlobSet() map[string]bool {
+	blobs := map[string]bool{}
+	for hash := range e.Blobs {
+		blobs[hash] = true
+	}
+	return blobs
+}
+
+// ListEntries walks the cache dir and returns all its entries.
+// Entries are stored in <cache dir>/<1 hex char>/<2 hex chars>/<rest of hash>.
+func ListEntries() ([]Entry, error) {
+	entries := []Entry{}
+
+	err := walkEntryParentDirs(func(parentDir string, prefix string) error {
+		parentEntries, err := listParentDir(parentDir, prefix)
+		entries = append(entries, parentEntries...)
+		return err
+	})
+
+	return entries, err
+}
+
+// listParentDir returns the entries of a <1 hex char>/<2 hex chars> dir.
+// Entries removed while listing, e.g. by a concurrent gc or quarantine, are
+// skipped.
+func listParentDir(parentDir string, prefix string) ([]Entry, error) {
+	names, err := readEntryDirs(parentDir, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("cannot read cache dir: %w", err)
+	}
+
+	entries := []Entry{}
+	for _, name := range names {
+		entry, err := readEntry(filepath.Join(parentDir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return entries, err
+		}
+		entry.Key = prefix + "/" + name
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// walkEntryParentDirs calls fn for each <1 hex char>/<2 hex chars> dir of the cache dir.
+func walkEntryParentDirs(fn func(parentDir string, prefix string) error) error {
+	cacheDir := config.Get().CacheDir
+
+	level1, err := readEntryDirs(cacheDir, 1)
+	if err != nil {
+		return fmt.Errorf("cannot read cache dir: %w", err)
+	}
+
+	for _, l1 := range level1 {
+		level2, err := readEntryDirs(filepath.Join(cacheDir, l1), 2)
+		// removed by a concurrent gc once empty
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("cannot read cache dir: %w", err)
+		}
+
+		for _, l2 := range level2 {
+			err = fn(filepath.Join(cacheDir, l1, l2), l1+"/"+l2)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// readEntryDirs returns the names of the sub directories made of hex chars.
+// When length is not zero, only names with that length are returned.
+func readEntryDirs(dir string, length int) ([]string, error) {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		if !dirEntry.IsDir() || (length != 0 && len(name) != length) || !isHex(name) {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func readEntry(dir string) (Entry, error) {
+	entry := Entry{Dir: dir}
+
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return Entry{}, fmt.Errorf("cannot read cache entry: %w", err)
+	}
+
+	names := map[string]bool{}
+	for _, dirEntry := range dirEntries {
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		names[dirEntry.Name()] = true
+		entry.Size += info.Size()
+		switch dirEntry.Name() {
+		case configFileName:
+			entry.LastUsed = info.ModTime()
+		case completeFileName:
+			entry.Complete = true
+		case restoredFileName:
+			entry.LastRestored = info.ModTime()
+		}
+	}
+
+	cacheConfig, err := LoadConfig(dir)
+	if err == nil {
+		entry.Blobs = map[string]int64{}
+		for _, file := range cacheConfig.OutputFiles {
+			name := file.BlobName()
+			// entries saved before blobs were shared hold them on the entry dir
+			if _, ok := entry.Blobs[name]; ok || names[name] || !storage.IsBlob(name) {
+				continue
+			}
+			info, err := os.Stat(blobPath(name))
+			if err != nil {
+				continue
+			}
+			entry.Blobs[name] = info.Size()
+			entry.Size += info.Size()
+		}
+	}
+
+	if entry.LastUsed.IsZero() {
+		// incomplete entry, use the dir time instead
+		info, err := os.Stat(dir)
+		if err != nil {
+			return Entry{}, fmt.Errorf("cannot read cache entry: %w", err)
+		}
+		entry.LastUsed = info.ModTime()
+	}
+
+	return entry, nil
+}
+
+// touchEntry marks an entry as used, by updating the modification time of its config.
+func touchEntry(cacheHitDir string) error {
+	return fs.Touch(GetConfigFilePath(cacheHitDir))
+}
+
+// markRestored records that an entry was restored, by updating the
+// modification time of its restored marker.
+func markRestored(cacheHitDir string) error {
+	p := filepath.Join(cacheHitDir, restoredFileName)
+	err := fs.Touch(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return os.WriteFile(p, []byte{}, config.Get().FilePerm())
+	}
+	return err
+}
+
+// removeEntry deletes an entry and the parent dirs that become empty.
+// Returns flock.ErrLocked when the entry is in use by another process.
+func removeEntry(entry Entry) error {
+	lock, err := tryLockEntry(entry.Dir, flock.Exclusive)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = lock.Release() }()
+
+	err = os.RemoveAll(entry.Dir)
+	if err != nil {
+		return fmt.Errorf("cannot remove cache entry %s: %w", entry.Key, err)
+	}
+
+	// fails when not empty, which is fine
+	l2Dir := filepath.Dir(entry.Dir)
+	if os.Remove(l2Dir) == nil {
+		_ = os.Remove(filepath.Dir(l2Dir))
+	}
+
+	return nil
+}
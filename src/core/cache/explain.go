@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"path"
+	"path/filepath"
+)
+
+// Explanation tells why a directive missed the cache, by comparing its key
+// with the closest entry saved for the same directive.
+type Explanation struct {
+	// closest entry, nil when none was saved for the directive
+	Entry *EntryDetails
+	// changes from the key of the entry to the current one
+	Changes []KeyChange
+}
+
+// Explain compares the key of a verified directive with the entries saved for
+// the same file and directive, found by line or by command. Files are matched
+// relative to the key root, so that entries saved from another checkout are
+// found too. The closest entry is the one with the fewest changes, or else the
+// most recently used one. Entries saved without a manifest are skipped.
+func Explain(result VerifyResult) (Explanation, error) {
+	details, err := Inspect()
+	if err != nil {
+		return Explanation{}, err
+	}
+
+	file := keyFile(result.Manifest, result.Opts.Path)
+	explanation := Explanation{}
+	// most recently used first
+	for i := len(details) - 1; i >= 0; i-- {
+		d := details[i]
+		if d.Error != nil || d.Config.Manifest == nil || keyFile(d.Config.Manifest, d.Config.File) != file ||
+			d.Config.Line != result.Opts.Line && d.Config.Command != result.Opts.Directive {
+			continue
+		}
+
+		changes := DiffManifests(d.Config.Manifest, result.Manifest)
+		if explanation.Entry == nil || len(changes) < len(explanation.Changes) {
+			explanation = Explanation{Entry: &d, Changes: changes}
+		}
+	}
+
+	return explanation, nil
+}
+
+// keyFile returns the path of the file of a directive as in its key: relative
+// to the key root, unless the file is outside of it.
+func keyFile(m KeyManifest, file string) string {
+	for _, c := range m {
+		if c.Kind == "dir" {
+			return path.Join(c.Value, filepath.Base(file))
+		}
+	}
+	return filepath.ToSlash(file)
+}
@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/oNaiPs/go-generate-fast/src/plugins"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplain(t *testing.T) {
+	setTempCacheDir(t)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(dir, "a.in"), []byte("a"), 0600))
+	require.NoError(t, os.WriteFile(path.Join(dir, "gen.txt"), []byte("generated"), 0600))
+
+	opts := plugins.GenerateOpts{
+		Path:                path.Join(dir, "gen.go"),
+		Line:                3,
+		Directive:           "go $GOCMD",
+		Words:               []string{"go", "version"},
+		ExecutableName:      "go",
+		ExtraInputPatterns:  []string{"*.in"},
+		ExtraOutputPatterns: []string{"gen.txt"},
+	}
+
+	verifyRes, err := Verify(opts)
+	require.NoError(t, err)
+	explanation, err := Explain(verifyRes)
+	require.NoError(t, err)
+	assert.Nil(t, explanation.Entry, "nothing saved yet")
+
+	require.NoError(t, Save(verifyRes))
+	cacheConfig, err := LoadConfig(verifyRes.CacheHitDir)
+	require.NoError(t, err)
+	assert.Equal(t, 3, cacheConfig.Line)
+	// variables are not expanded, their values could be secrets
+	assert.Equal(t, "go $GOCMD", cacheConfig.Command)
+	assert.Equal(t, verifyRes.Manifest, cacheConfig.Manifest)
+
+	// an input changed, and another one was added
+	require.NoError(t, os.WriteFile(path.Join(dir, "a.in"), []byte("changed"), 0600))
+	require.NoError(t, os.WriteFile(path.Join(dir, "b.in"), []byte("b"), 0600))
+	changedRes, err := Verify(opts)
+	require.NoError(t, err)
+	require.False(t, changedRes.CacheHit)
+
+	explanation, err = Explain(changedRes)
+	require.NoError(t, err)
+	require.NotNil(t, explanation.Entry)
+	assert.Equal(t, verifyRes.CacheKey, explanation.Entry.Key)
+	require.Len(t, explanation.Changes, 2)
+	assert.Equal(t, ComponentChanged, explanation.Changes[0].Change)
+	assert.Equal(t, "a.in", path.Base(explanation.Changes[0].Name))
+	assert.Equal(t, ComponentAdded, explanation.Changes[1].Change)
+	assert.Equal(t, "b.in", path.Base(explanation.Changes[1].Name))
+
+	// the directive moved, with another command
+	opts.Line = 4
+	opts.Directive = "go env"
+	opts.Words = []string{"go", "env"}
+	movedRes, err := Verify(opts)
+	require.NoError(t, err)
+	explanation, err = Explain(movedRes)
+	require.NoError(t, err)
+	assert.Nil(t, explanation.Entry)
+
+	// the same directive on another checkout
+	other := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(other, "a.in"), []byte("other"), 0600))
+	opts.Path = path.Join(other, "gen.go")
+	opts.Line = 3
+	otherRes, err := Verify(opts)
+	require.NoError(t, err)
+	explanation, err = Explain(otherRes)
+	require.NoError(t, err)
+	require.NotNil(t, explanation.Entry)
+	assert.Equal(t, verifyRes.CacheKey, explanation.Entry.Key)
+}
+
+func TestManifestArgsNotExpanded(t *testing.T) {
+	dir := t.TempDir()
+	opts := plugins.GenerateOpts{
+		Path:           path.Join(dir, "gen.go"),
+		Directive:      "go $GOCMD",
+		Words:          []string{"go", "secret"},
+		Env:            map[string]string{"GOCMD": "secret"},
+		ExecutableName: "go",
+	}
+
+	dir1, m, err := computeKey(opts, plugins.InputOutputFiles{})
+	require.NoError(t, err)
+	assert.Contains(t, m, KeyComponent{Kind: "arg", Name: "1", Value: "$GOCMD"})
+	for _, c := range m {
+		assert.NotContains(t, c.Value, "secret")
+	}
+
+	// still part of the key, through the env
+	opts.Words = []string{"go", "other"}
+	opts.Env = map[string]string{"GOCMD": "other"}
+	dir2, _, err := computeKey(opts, plugins.InputOutputFiles{})
+	require.NoError(t, err)
+	assert.NotEqual(t, dir1, dir2)
+}
@@ -0,0 +1,204 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/oNaiPs/go-generate-fast/src/core/config"
+	"github.com/oNaiPs/go-generate-fast/src/utils/flock"
+	"github.com/oNaiPs/go-generate-fast/src/utils/fs"
+	"github.com/oNaiPs/go-generate-fast/src/utils/str"
+	"go.uber.org/zap"
+)
+
+const gcStampFileName = "gc.stamp"
+
+type GCOptions struct {
+	// maximum total size of the cache, zero for no limit
+	MaxSize int64
+	// maximum time since an entry was last used, zero for no limit
+	MaxAge time.Duration
+	// only report what would be removed
+	DryRun bool
+}
+
+type GCResult struct {
+	Removed        int
+	RemovedSize    int64
+	Remaining      int
+	RemainingSize  int64
+	RemovedEntries []Entry
+}
+
+// GC removes least recently used entries until the cache fits within the
+// configured limits. Entries older than MaxAge are always removed.
+// Entries in use by other processes are kept.
+func GC(opts GCOptions) (GCResult, error) {
+	lockPath, err := locksFilePath(gcLockFileName)
+	if err != nil {
+		return GCResult{}, err
+	}
+
+	// a single gc at a time, so that concurrent runs do not both trim the same entries
+	lock, err := flock.Acquire(lockPath, config.Get().FilePerm(), flock.Exclusive)
+	if err != nil {
+		return GCResult{}, fmt.Errorf("cannot lock cache gc: %w", err)
+	}
+	defer func() { _ = lock.Release() }()
+
+	return gc(opts)
+}
+
+func gc(opts GCOptions) (GCResult, error) {
+	entries, err := ListEntries()
+	if err != nil {
+		return GCResult{}, err
+	}
+
+	// most recently used first
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastUsed.After(entries[j].LastUsed)
+	})
+
+	result := GCResult{}
+	// blobs referenced by the kept entries, they are only counted once
+	referenced := map[string]bool{}
+	keep := func(entry Entry, size int64) {
+		result.Remaining++
+		result.RemainingSize += size
+		for name := range entry.Blobs {
+			referenced[name] = true
+		}
+	}
+
+	now := time.Now()
+	for _, entry := range entries {
+		size := unreferencedSize(entry, referenced)
+		expired := opts.MaxAge > 0 && now.Sub(entry.LastUsed) > opts.MaxAge
+		oversized := opts.MaxSize > 0 && result.RemainingSize+size > opts.MaxSize
+		// leftover of an interrupted save or upload
+		abandoned := !entry.Complete && now.Sub(entry.LastUsed) > staleStagingAge
+
+		if !expired && !oversized && !abandoned {
+			keep(entry, size)
+			continue
+		}
+
+		if !opts.DryRun {
+			err := removeEntry(entry)
+			if errors.Is(err, flock.ErrLocked) {
+				zap.S().Debugf("Keeping cache entry %s, in use", entry.Key)
+				keep(entry, size)
+				continue
+			}
+			if err != nil {
+				return result, err
+			}
+		}
+		zap.S().Debugf("Removed cache entry %s (%s, last used %s)", entry.Key, str.FormatSize(entry.Size), entry.LastUsed.Format(time.RFC3339))
+
+		// its blobs are only freed below, when no kept entry references them
+		result.Removed++
+		result.RemovedSize += unreferencedSize(entry, entry.BlobSet())
+		result.RemovedEntries = append(result.RemovedEntries, entry)
+	}
+
+	_, blobsSize, err := sweepBlobs(referenced, opts.DryRun)
+	if err != nil {
+		return result, err
+	}
+	result.RemovedSize += blobsSize
+
+	if !opts.DryRun {
+		removeExpiredQuarantine()
+		err = walkEntryParentDirs(func(parentDir string, prefix string) error {
+			removeStaleStagingDirs(parentDir)
+			return nil
+		})
+	}
+
+	return result, err
+}
+
+// unreferencedSize returns the size of the entry, without the blobs in referenced.
+func unreferencedSize(entry Entry, referenced map[string]bool) int64 {
+	size := entry.Size
+	for name, blobSize := range entry.Blobs {
+		if referenced[name] {
+			size -= blobSize
+		}
+	}
+	return size
+}
+
+// AutoGC runs the garbage collection with the configured limits, at most once
+// every configured interval.
+func AutoGC() {
+	conf := config.Get()
+	if conf.MaxSize == 0 && conf.MaxAge == 0 {
+		return
+	}
+
+	stampFile := filepath.Join(conf.CacheDir, gcStampFileName)
+	info, err := os.Stat(stampFile)
+	if err == nil && time.Since(info.ModTime()) < conf.GCInterval {
+		return
+	}
+
+	err = writeStamp(stampFile)
+	if err != nil {
+		zap.S().Errorf("cannot write gc stamp: %s", err)
+		return
+	}
+
+	lockPath, err := locksFilePath(gcLockFileName)
+	if err != nil {
+		zap.S().Errorf("cannot garbage collect cache: %s", err)
+		return
+	}
+
+	lock, err := flock.TryAcquire(lockPath, conf.FilePerm(), flock.Exclusive)
+	if errors.Is(err, flock.ErrLocked) {
+		zap.S().Debugf("Cache gc already running on another process")
+		return
+	}
+	if err != nil {
+		zap.S().Errorf("cannot lock cache gc: %s", err)
+		return
+	}
+	defer func() { _ = lock.Release() }()
+
+	result, err := gc(GCOptions{MaxSize: conf.MaxSize, MaxAge: conf.MaxAge})
+	if err != nil {
+		zap.S().Errorf("cannot garbage collect cache: %s", err)
+		return
+	}
+
+	if result.Removed > 0 {
+		zap.S().Debugf("Cache gc: %s", result)
+	}
+}
+
+// writeStamp creates the stamp file, or updates its modification time when it exists.
+func writeStamp(stampFile string) error {
+	f, err := os.OpenFile(stampFile, os.O_RDONLY|os.O_CREATE|os.O_EXCL, config.Get().FilePerm())
+	if err == nil {
+		_ = f.Close()
+		// other users of a shared cache dir must be able to touch it
+		err = os.Chmod(stampFile, config.Get().FilePerm())
+	}
+	if err != nil && !errors.Is(err, os.ErrExist) {
+		return err
+	}
+
+	return fs.Touch(stampFile)
+}
+
+func (r GCResult) String() string {
+	return fmt.Sprintf("removed %d entries (%s), %d entries remaining (%s)",
+		r.Removed, str.FormatSize(r.RemovedSize), r.Remaining, str.FormatSize(r.RemainingSize))
+}
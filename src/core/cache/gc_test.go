@@ -0,0 +1,220 @@
+package cache
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oNaiPs/go-generate-fast/src/core/config"
+	"github.com/oNaiPs/go-generate-fast/src/plugins"
+	util_test "github.com/oNaiPs/go-generate-fast/src/test"
+	"github.com/stretchr/testify/assert"
+)
+
+// saveTestEntry saves an entry of about size bytes, last used at the given time.
+func saveTestEntry(t *testing.T, key string, size int, lastUsed time.Time) VerifyResult {
+	t.Helper()
+
+	// distinct contents, so that entries do not share blobs
+	content := []byte(strings.Repeat(key, size/len(key)+1))[:size]
+	output := util_test.WriteTempFile(t, string(content))
+	verifyRes := VerifyResult{
+		CacheHitDir: path.Join(config.Get().CacheDir, key),
+		CacheKey:    key,
+		IoFiles: plugins.InputOutputFiles{
+			OutputFiles: []string{output.Name()},
+		},
+	}
+
+	assert.NoError(t, Save(verifyRes))
+	assert.NoError(t, os.Chtimes(GetConfigFilePath(verifyRes.CacheHitDir), lastUsed, lastUsed))
+
+	return verifyRes
+}
+
+func TestListEntries(t *testing.T) {
+	setTempCacheDir(t)
+
+	now := time.Now().Truncate(time.Second)
+	saveTestEntry(t, "a/bc/def", 100, now)
+	saveTestEntry(t, "0/12/345", 200, now.Add(-time.Hour))
+
+	// not entries
+	assert.NoError(t, os.MkdirAll(path.Join(config.Get().CacheDir, "tmp", "12", "345"), 0700))
+	assert.NoError(t, os.MkdirAll(path.Join(config.Get().CacheDir, "a", "xyz"), 0700))
+	assert.NoError(t, os.WriteFile(path.Join(config.Get().CacheDir, gcStampFileName), []byte{}, 0600))
+
+	entries, err := ListEntries()
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	assert.Equal(t, "0/12/345", entries[0].Key)
+	assert.Equal(t, now.Add(-time.Hour), entries[0].LastUsed)
+	assert.Greater(t, entries[0].Size, int64(200))
+
+	assert.Equal(t, "a/bc/def", entries[1].Key)
+	assert.Equal(t, path.Join(config.Get().CacheDir, "a", "bc", "def"), entries[1].Dir)
+	assert.Equal(t, now, entries[1].LastUsed)
+}
+
+func TestListEntriesRemovedWhileListing(t *testing.T) {
+	setTempCacheDir(t)
+
+	saveTestEntry(t, "a/bc/aaa", 10, time.Now())
+	removed := saveTestEntry(t, "a/bc/bbb", 10, time.Now())
+
+	// removed after the parent dir was read
+	names, err := readEntryDirs(path.Dir(removed.CacheHitDir), 0)
+	assert.NoError(t, err)
+	assert.Len(t, names, 2)
+	assert.NoError(t, os.RemoveAll(removed.CacheHitDir))
+	_, err = readEntry(removed.CacheHitDir)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	entries, err := ListEntries()
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "a/bc/aaa", entries[0].Key)
+
+	// and so was the whole parent dir
+	entries, err = listParentDir(path.Join(config.Get().CacheDir, "f", "ff"), "f/ff")
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestRestoreUpdatesLastUsed(t *testing.T) {
+	setTempCacheDir(t)
+
+	lastUsed := time.Now().Add(-24 * time.Hour)
+	verifyRes := saveTestEntry(t, "a/bc/def", 10, lastUsed)
+
+	_, err := Restore(verifyRes)
+	assert.NoError(t, err)
+
+	entries, err := ListEntries()
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.True(t, entries[0].LastUsed.After(lastUsed))
+	assert.False(t, entries[0].LastRestored.IsZero())
+}
+
+func TestRestoredIsRecorded(t *testing.T) {
+	setTempCacheDir(t)
+
+	// used again right away, and touched long after being saved
+	verifyRes := saveTestEntry(t, "a/bc/def", 10, time.Now())
+	details, err := Inspect()
+	assert.NoError(t, err)
+	assert.False(t, details[0].Restored())
+
+	assert.NoError(t, touchEntry(verifyRes.CacheHitDir))
+	details, err = Inspect()
+	assert.NoError(t, err)
+	assert.False(t, details[0].Restored())
+
+	_, err = Restore(verifyRes)
+	assert.NoError(t, err)
+	details, err = Inspect()
+	assert.NoError(t, err)
+	assert.True(t, details[0].Restored())
+}
+
+func TestGC(t *testing.T) {
+	setTempCacheDir(t)
+
+	now := time.Now()
+	saveTestEntry(t, "a/bc/aaa", 1000, now)
+	saveTestEntry(t, "a/bc/bbb", 1000, now.Add(-time.Hour))
+	saveTestEntry(t, "b/cd/ccc", 1000, now.Add(-48*time.Hour))
+
+	// dry run does not remove anything
+	result, err := GC(GCOptions{MaxAge: 24 * time.Hour, DryRun: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Removed)
+	assert.Equal(t, "b/cd/ccc", result.RemovedEntries[0].Key)
+	assert.DirExists(t, path.Join(config.Get().CacheDir, "b", "cd", "ccc"))
+
+	result, err = GC(GCOptions{MaxAge: 24 * time.Hour})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Removed)
+	assert.Equal(t, 2, result.Remaining)
+	assert.NoDirExists(t, path.Join(config.Get().CacheDir, "b"), "empty parent dirs shall be removed")
+
+	// only the most recently used entry fits
+	result, err = GC(GCOptions{MaxSize: 1500})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Removed)
+	assert.Equal(t, "a/bc/bbb", result.RemovedEntries[0].Key)
+	assert.Equal(t, 1, result.Remaining)
+	assert.LessOrEqual(t, result.RemainingSize, int64(1500))
+	assert.DirExists(t, path.Join(config.Get().CacheDir, "a", "bc", "aaa"))
+}
+
+func TestAutoGC(t *testing.T) {
+	setTempCacheDir(t)
+
+	oldMaxAge := config.Get().MaxAge
+	config.Get().MaxAge = 24 * time.Hour
+	t.Cleanup(func() { config.Get().MaxAge = oldMaxAge })
+
+	saveTestEntry(t, "b/cd/ccc", 10, time.Now().Add(-48*time.Hour))
+	AutoGC()
+	assert.NoDirExists(t, path.Join(config.Get().CacheDir, "b", "cd", "ccc"))
+	assert.FileExists(t, path.Join(config.Get().CacheDir, gcStampFileName))
+
+	// does not run again within the interval
+	saveTestEntry(t, "b/cd/ccc", 10, time.Now().Add(-48*time.Hour))
+	AutoGC()
+	assert.DirExists(t, path.Join(config.Get().CacheDir, "b", "cd", "ccc"))
+}
+
+func TestGCSharedBlobs(t *testing.T) {
+	setTempCacheDir(t)
+
+	now := time.Now()
+	shared := util_test.WriteTempFile(t, strings.Repeat("shared", 100))
+	saveShared := func(key string, lastUsed time.Time) VerifyResult {
+		verifyRes := VerifyResult{
+			CacheHitDir: path.Join(config.Get().CacheDir, key),
+			IoFiles:     plugins.InputOutputFiles{OutputFiles: []string{shared.Name()}},
+		}
+		assert.NoError(t, Save(verifyRes))
+		assert.NoError(t, os.Chtimes(GetConfigFilePath(verifyRes.CacheHitDir), lastUsed, lastUsed))
+		return verifyRes
+	}
+
+	saveShared("a/bc/aaa", now)
+	old := saveShared("b/cd/bbb", now.Add(-48*time.Hour))
+
+	cacheConfig, err := LoadConfig(old.CacheHitDir)
+	assert.NoError(t, err)
+	blob := blobPath(cacheConfig.OutputFiles[0].Hash)
+	assert.FileExists(t, blob)
+	assert.NoFileExists(t, path.Join(old.CacheHitDir, cacheConfig.OutputFiles[0].Hash), "blobs shall not be stored on entries")
+
+	details, err := Inspect()
+	assert.NoError(t, err)
+	stats := ComputeStats(details)
+	assert.Equal(t, int64(600), stats.SharedSize, "shared blob shall be counted once")
+
+	// the blob is still referenced by the kept entry
+	result, err := GC(GCOptions{MaxAge: 24 * time.Hour})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Removed)
+	assert.FileExists(t, blob)
+
+	// unreferenced blobs are only swept once they were not used for a while
+	assert.NoError(t, os.RemoveAll(path.Join(config.Get().CacheDir, "a")))
+	result, err = GC(GCOptions{MaxAge: 24 * time.Hour})
+	assert.NoError(t, err)
+	assert.FileExists(t, blob)
+
+	past := now.Add(-2 * staleStagingAge)
+	assert.NoError(t, os.Chtimes(blob, past, past))
+	result, err = GC(GCOptions{MaxAge: 24 * time.Hour})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(600), result.RemovedSize)
+	assert.NoFileExists(t, blob)
+}
@@ -0,0 +1,149 @@
+package cache
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/oNaiPs/go-generate-fast/src/plugins"
+	"github.com/oNaiPs/go-generate-fast/src/utils/fs"
+	"golang.org/x/tools/go/packages"
+)
+
+// goRunInputs is what an unversioned `go run` generator is built from.
+type goRunInputs struct {
+	// files of the packages from the main module or from local replacements,
+	// and the go.mod and go.sum of the main module when the generator is local
+	Files []string
+	// path@version and go.sum hash of the modules of the other packages
+	Modules []string
+}
+
+// goRunDeps resolves the packages an unversioned `go run` generator is built
+// from, honoring its build flags. Local generators are identified by their
+// files, and the modules they depend on are pinned by go.mod and go.sum. Other
+// generators are identified by the versions the module graph selects for them.
+func goRunDeps(opts plugins.GenerateOpts) (goRunInputs, error) {
+	dir := opts.Dir()
+	var buildFlags []string
+	for _, flag := range opts.GoBuildFlags {
+		name, value, _ := strings.Cut(strings.TrimLeft(flag, "-"), "=")
+		switch name {
+		case "C":
+			dir = fs.ResolvePath(dir, value)
+		case "exec":
+			// only known by go run
+		default:
+			buildFlags = append(buildFlags, flag)
+		}
+	}
+
+	patterns := opts.GoFiles
+	if len(patterns) == 0 {
+		patterns = []string{opts.GoPackage}
+	}
+
+	pkgs, err := opts.Loader.Deps(dir, patterns, buildFlags)
+	if err != nil {
+		return goRunInputs{}, fmt.Errorf("cannot load go run package %s: %w", opts.GoPackage, err)
+	}
+	if len(pkgs) != 1 {
+		return goRunInputs{}, fmt.Errorf("cannot load go run package %s: %d packages found", opts.GoPackage, len(pkgs))
+	}
+
+	root := pkgs[0]
+	local := isLocalModule(root.Module) || root.Module == nil && isLocalPattern(opts)
+	goSum := readGoSum(fs.FindGoMod(dir))
+
+	files := map[string]bool{}
+	modules := map[string]bool{}
+	packages.Visit(pkgs, nil, func(p *packages.Package) {
+		if p == root && local || isLocalModule(p.Module) {
+			for _, list := range [][]string{p.GoFiles, p.OtherFiles, p.EmbedFiles} {
+				for _, file := range list {
+					files[file] = true
+				}
+			}
+			if local && p.Module != nil && p.Module.Main {
+				addGoModFiles(files, p.Module.GoMod)
+			}
+			return
+		}
+
+		// standard library
+		if p.Module == nil {
+			return
+		}
+
+		m := p.Module
+		if m.Replace != nil {
+			m = m.Replace
+		}
+		modules[m.Path+"@"+m.Version+" "+goSum[m.Path+" "+m.Version]] = true
+	})
+
+	// go files are not given a module, although built with the one containing them
+	if local && root.Module == nil && len(root.GoFiles) > 0 {
+		addGoModFiles(files, fs.FindGoMod(filepath.Dir(root.GoFiles[0])))
+	}
+
+	return goRunInputs{
+		Files:   slices.Sorted(maps.Keys(files)),
+		Modules: slices.Sorted(maps.Keys(modules)),
+	}, nil
+}
+
+// readGoSum returns the hashes of the module contents listed in the go.sum next
+// to a go.mod file, by "<module path> <version>".
+func readGoSum(goMod string) map[string]string {
+	sums := map[string]string{}
+	if goMod == "" {
+		return sums
+	}
+
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(goMod), "go.sum"))
+	if err != nil {
+		return sums
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		// <module path> <version>[/go.mod] <hash>
+		fields := strings.Fields(line)
+		if len(fields) != 3 || strings.HasSuffix(fields[1], "/go.mod") {
+			continue
+		}
+		sums[fields[0]+" "+fields[1]] = fields[2]
+	}
+	return sums
+}
+
+// addGoModFiles adds a go.mod file and the go.sum next to it, when they exist.
+func addGoModFiles(files map[string]bool, goMod string) {
+	if goMod == "" {
+		return
+	}
+	files[goMod] = true
+
+	goSum := filepath.Join(filepath.Dir(goMod), "go.sum")
+	if _, err := os.Stat(goSum); err == nil {
+		files[goSum] = true
+	}
+}
+
+// isLocalModule reports whether the sources of a module are on the local
+// filesystem, being the main module or replaced by a local directory.
+func isLocalModule(m *packages.Module) bool {
+	return m != nil && (m.Main || m.Replace != nil && m.Replace.Version == "")
+}
+
+// isLocalPattern reports whether go run was given a file or directory path,
+// which is local even outside of a module.
+func isLocalPattern(opts plugins.GenerateOpts) bool {
+	return len(opts.GoFiles) > 0 ||
+		filepath.IsAbs(opts.GoPackage) ||
+		opts.GoPackage == "." || opts.GoPackage == ".." ||
+		strings.HasPrefix(opts.GoPackage, "./") || strings.HasPrefix(opts.GoPackage, "../")
+}
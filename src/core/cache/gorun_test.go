@@ -0,0 +1,150 @@
+package cache
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/oNaiPs/go-generate-fast/src/plugins"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeGoRunModule(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":               "module example.com/mod\n\ngo 1.21\n",
+		"gen.go":               "package mod\n",
+		"cmd/gen/main.go":      "package main\n\nimport \"example.com/mod/internal/lib\"\n\nfunc main() { lib.Gen() }\n",
+		"cmd/gen/tagged.go":    "//go:build extra\n\npackage main\n",
+		"internal/lib/lib.go":  "package lib\n\nfunc Gen() {}\n",
+		"internal/other/o.go":  "package other\n",
+		"scripts/gen_files.go": "//go:build ignore\n\npackage main\n\nfunc main() {}\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.MkdirAll(path.Dir(path.Join(dir, name)), 0700))
+		require.NoError(t, os.WriteFile(path.Join(dir, name), []byte(content), 0600))
+	}
+	return dir
+}
+
+func TestGoRunDepsLocal(t *testing.T) {
+	dir := writeGoRunModule(t)
+
+	opts := plugins.GenerateOpts{
+		Path:      path.Join(dir, "gen.go"),
+		GoPackage: "./cmd/gen",
+	}
+	deps, err := goRunDeps(opts)
+	assert.NoError(t, err)
+	assert.Empty(t, deps.Modules)
+	assert.Equal(t, []string{
+		path.Join(dir, "cmd/gen/main.go"),
+		path.Join(dir, "go.mod"),
+		path.Join(dir, "internal/lib/lib.go"),
+	}, deps.Files)
+
+	// same package, by import path and with build tags
+	opts.GoPackage = "example.com/mod/cmd/gen"
+	opts.GoBuildFlags = []string{"-tags=extra"}
+	deps, err = goRunDeps(opts)
+	assert.NoError(t, err)
+	assert.Contains(t, deps.Files, path.Join(dir, "cmd/gen/tagged.go"))
+
+	// go files
+	opts = plugins.GenerateOpts{
+		Path:      path.Join(dir, "gen.go"),
+		GoPackage: "scripts/gen_files.go",
+		GoFiles:   []string{"scripts/gen_files.go"},
+	}
+	deps, err = goRunDeps(opts)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{path.Join(dir, "go.mod"), path.Join(dir, "scripts/gen_files.go")}, deps.Files)
+
+	// standard library
+	opts = plugins.GenerateOpts{
+		Path:      path.Join(dir, "gen.go"),
+		GoPackage: "cmd/gofmt",
+	}
+	deps, err = goRunDeps(opts)
+	assert.NoError(t, err)
+	assert.Empty(t, deps.Files)
+	assert.Empty(t, deps.Modules)
+
+	opts.GoPackage = "./cmd/missing"
+	_, err = goRunDeps(opts)
+	assert.Error(t, err)
+}
+
+// writeVendoredTool writes a module requiring a vendored tool module, which
+// is loaded without network access.
+func writeVendoredTool(t *testing.T, dir string, version string) {
+	t.Helper()
+
+	files := map[string]string{
+		"go.mod":             "module example.com/mod\n\ngo 1.21\n\nrequire example.com/tool " + version + "\n",
+		"go.sum":             "example.com/tool " + version + " h1:" + version + "=\nexample.com/tool " + version + "/go.mod h1:mod=\n",
+		"gen.go":             "package mod\n",
+		"vendor/modules.txt": "# example.com/tool " + version + "\n## explicit; go 1.21\nexample.com/tool/cmd/tool\n",
+		"vendor/example.com/tool/cmd/tool/main.go": "package main\n\nfunc main() {}\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.MkdirAll(path.Dir(path.Join(dir, name)), 0700))
+		require.NoError(t, os.WriteFile(path.Join(dir, name), []byte(content), 0600))
+	}
+}
+
+func TestGoRunDepsModule(t *testing.T) {
+	t.Setenv("GOFLAGS", "-mod=vendor")
+	t.Setenv("GOPROXY", "off")
+
+	dir := t.TempDir()
+	writeVendoredTool(t, dir, "v1.2.3")
+
+	opts := plugins.GenerateOpts{
+		Path:      path.Join(dir, "gen.go"),
+		Words:     []string{"go", "run", "example.com/tool/cmd/tool"},
+		GoPackage: "example.com/tool/cmd/tool",
+	}
+	deps, err := goRunDeps(opts)
+	assert.NoError(t, err)
+	assert.Empty(t, deps.Files)
+	assert.Equal(t, []string{"example.com/tool@v1.2.3 h1:v1.2.3="}, deps.Modules)
+
+	dir1, err := calculateCacheDirectoryFromInputData(opts, plugins.InputOutputFiles{})
+	assert.NoError(t, err)
+
+	// bumped dependency
+	writeVendoredTool(t, dir, "v1.3.0")
+	dir2, err := calculateCacheDirectoryFromInputData(opts, plugins.InputOutputFiles{})
+	assert.NoError(t, err)
+	assert.NotEqual(t, dir1, dir2)
+}
+
+func TestGoRunCacheKey(t *testing.T) {
+	dir := writeGoRunModule(t)
+
+	opts := plugins.GenerateOpts{
+		Path:      path.Join(dir, "gen.go"),
+		Words:     []string{"go", "run", "./cmd/gen"},
+		GoPackage: "./cmd/gen",
+	}
+	ioFiles := plugins.InputOutputFiles{}
+
+	dir1, err := calculateCacheDirectoryFromInputData(opts, ioFiles)
+	assert.NoError(t, err)
+
+	// unrelated package
+	require.NoError(t, os.WriteFile(path.Join(dir, "internal/other/o.go"), []byte("package other\n\nvar A = 1\n"), 0600))
+	dir2, err := calculateCacheDirectoryFromInputData(opts, ioFiles)
+	assert.NoError(t, err)
+	assert.Equal(t, dir1, dir2)
+
+	// dependency of the generator
+	require.NoError(t, os.WriteFile(path.Join(dir, "internal/lib/lib.go"), []byte("package lib\n\nfunc Gen() { println() }\n"), 0600))
+	dir3, err := calculateCacheDirectoryFromInputData(opts, ioFiles)
+	assert.NoError(t, err)
+	assert.NotEqual(t, dir2, dir3)
+}
@@ -0,0 +1,130 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/oNaiPs/go-generate-fast/src/core/goenv"
+	"github.com/oNaiPs/go-generate-fast/src/plugins"
+	"github.com/oNaiPs/go-generate-fast/src/utils/fs"
+	"golang.org/x/mod/modfile"
+)
+
+var majorVersionElem = regexp.MustCompile(`^v[0-9]+$`)
+
+// goToolInfo identifies the tool run by a `go tool` command, without building
+// it. Tools declared in go.mod are identified by the path, version and go.sum
+// hash of the module providing them, or by their sources when local. Other
+// tools come with the toolchain. The toolchain version is always included.
+func goToolInfo(opts plugins.GenerateOpts) (KeyManifest, error) {
+	env, err := goenv.Get(opts.Dir())
+	if err != nil {
+		return nil, err
+	}
+	m := KeyManifest{{Kind: "go", Value: env.GOVERSION}}
+
+	goMod := fs.FindGoMod(opts.Dir())
+	if goMod != "" {
+		data, err := os.ReadFile(goMod)
+		if err != nil {
+			return nil, err
+		}
+		mf, err := modfile.Parse(goMod, data, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if toolPath := findTool(mf, opts.GoTool); toolPath != "" {
+			modPath, version, local := toolModule(mf, toolPath)
+			if !local {
+				goSum := readGoSum(goMod)[modPath+" "+version]
+				m.add("tool", toolPath, fmt.Sprintf("%s@%s %s", modPath, version, goSum))
+				return m, nil
+			}
+
+			toolOpts := opts
+			toolOpts.GoPackage = toolPath
+			toolOpts.GoFiles = nil
+			toolOpts.GoBuildFlags = nil
+			deps, err := goRunDeps(toolOpts)
+			if err != nil {
+				return nil, err
+			}
+			sourceFiles, err := keyInputFiles(opts, keyRoot(opts), deps.Files)
+			if err != nil {
+				return nil, err
+			}
+			m.add("tool", toolPath, "")
+			m = append(m, sourceFiles...)
+			return append(m, keyModules(deps.Modules)...), nil
+		}
+	}
+
+	exe := opts.GoTool
+	if runtime.GOOS == "windows" {
+		exe += ".exe"
+	}
+	_, err = os.Stat(filepath.Join(env.GOTOOLDIR, exe))
+	if strings.Contains(opts.GoTool, "/") || err != nil {
+		return nil, fmt.Errorf("no tool %s declared in go.mod nor provided by %s", opts.GoTool, env.GOVERSION)
+	}
+	m.add("tool", opts.GoTool, "")
+	return m, nil
+}
+
+// findTool returns the package path of the tool declared in go.mod run by
+// `go tool <name>`, given by its path or by its command name.
+func findTool(mf *modfile.File, name string) string {
+	for _, tool := range mf.Tool {
+		if tool.Path == name || toolCommandName(tool.Path) == name {
+			return tool.Path
+		}
+	}
+	return ""
+}
+
+// toolCommandName returns the name of the command built from a package, like
+// the go command does: its last path element, unless it is a major version
+// suffix.
+func toolCommandName(pkgPath string) string {
+	name := path.Base(pkgPath)
+	if majorVersionElem.MatchString(name) && path.Dir(pkgPath) != "." {
+		name = path.Base(path.Dir(pkgPath))
+	}
+	return name
+}
+
+// toolModule returns the module providing a tool package and its version,
+// following replacements. Returns true when the sources of the module are
+// local, in the main module or replaced by a directory.
+func toolModule(mf *modfile.File, toolPath string) (string, string, bool) {
+	if mf.Module != nil && hasPathPrefix(toolPath, mf.Module.Mod.Path) {
+		return mf.Module.Mod.Path, "", true
+	}
+
+	modPath, version := "", ""
+	for _, r := range mf.Require {
+		if hasPathPrefix(toolPath, r.Mod.Path) && len(r.Mod.Path) > len(modPath) {
+			modPath, version = r.Mod.Path, r.Mod.Version
+		}
+	}
+
+	for _, r := range mf.Replace {
+		if r.Old.Path == modPath && (r.Old.Version == "" || r.Old.Version == version) {
+			if r.New.Version == "" {
+				return modPath, "", true
+			}
+			return r.New.Path, r.New.Version, false
+		}
+	}
+	return modPath, version, false
+}
+
+func hasPathPrefix(p string, prefix string) bool {
+	return p == prefix || strings.HasPrefix(p, prefix+"/")
+}
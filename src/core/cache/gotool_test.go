@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/oNaiPs/go-generate-fast/src/core/goenv"
+	"github.com/oNaiPs/go-generate-fast/src/plugins"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeGoToolModule(t *testing.T, dir string, version string) {
+	t.Helper()
+
+	files := map[string]string{
+		"go.mod": "module example.com/mod\n\ngo 1.24\n\n" +
+			"tool (\n\texample.com/mod/cmd/gen\n\texample.com/tool/v2/cmd/tool/v2\n)\n\n" +
+			"require example.com/tool/v2 " + version + "\n",
+		"go.sum":              "example.com/tool/v2 " + version + " h1:" + version + "=\n",
+		"gen.go":              "package mod\n",
+		"cmd/gen/main.go":     "package main\n\nfunc main() {}\n",
+		"internal/lib/lib.go": "package lib\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.MkdirAll(path.Dir(path.Join(dir, name)), 0700))
+		require.NoError(t, os.WriteFile(path.Join(dir, name), []byte(content), 0600))
+	}
+}
+
+func TestGoToolInfo(t *testing.T) {
+	dir := t.TempDir()
+	writeGoToolModule(t, dir, "v2.1.0")
+
+	env, err := goenv.Get(dir)
+	require.NoError(t, err)
+
+	// declared in go.mod, by command name
+	opts := plugins.GenerateOpts{
+		Path:   path.Join(dir, "gen.go"),
+		GoTool: "tool",
+	}
+	info, err := goToolInfo(opts)
+	assert.NoError(t, err)
+	assert.Equal(t, KeyManifest{
+		{Kind: "go", Value: env.GOVERSION},
+		{Kind: "tool", Name: "example.com/tool/v2/cmd/tool/v2", Value: "example.com/tool/v2@v2.1.0 h1:v2.1.0="},
+	}, info)
+
+	// and by path
+	opts.GoTool = "example.com/tool/v2/cmd/tool/v2"
+	info2, err := goToolInfo(opts)
+	assert.NoError(t, err)
+	assert.Equal(t, info, info2)
+
+	// bumped in go.mod
+	writeGoToolModule(t, dir, "v2.2.0")
+	info2, err = goToolInfo(opts)
+	assert.NoError(t, err)
+	assert.NotEqual(t, info, info2)
+
+	// provided by the toolchain
+	opts.GoTool = "compile"
+	info, err = goToolInfo(opts)
+	assert.NoError(t, err)
+	assert.Equal(t, KeyManifest{{Kind: "go", Value: env.GOVERSION}, {Kind: "tool", Name: "compile"}}, info)
+
+	opts.GoTool = "missing"
+	_, err = goToolInfo(opts)
+	assert.ErrorContains(t, err, "no tool missing")
+}
+
+func TestGoToolInfoLocal(t *testing.T) {
+	dir := t.TempDir()
+	writeGoToolModule(t, dir, "v2.1.0")
+
+	opts := plugins.GenerateOpts{
+		Path:   path.Join(dir, "gen.go"),
+		GoTool: "gen",
+	}
+	info, err := goToolInfo(opts)
+	assert.NoError(t, err)
+	assert.Contains(t, info, KeyComponent{Kind: "tool", Name: "example.com/mod/cmd/gen"})
+	assert.Equal(t, "cmd/gen/main.go", info[2].Name)
+
+	require.NoError(t, os.WriteFile(path.Join(dir, "cmd/gen/main.go"), []byte("package main\n\nfunc main() { println() }\n"), 0600))
+	info2, err := goToolInfo(opts)
+	assert.NoError(t, err)
+	assert.NotEqual(t, info, info2)
+}
+
+func TestToolCommandName(t *testing.T) {
+	assert.Equal(t, "stringer", toolCommandName("golang.org/x/tools/cmd/stringer"))
+	assert.Equal(t, "tool", toolCommandName("example.com/tool/v2"))
+	assert.Equal(t, "v2", toolCommandName("v2"))
+}
@@ -0,0 +1,183 @@
+package cache
+
+import (
+	"cmp"
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/oNaiPs/go-generate-fast/src/core/config"
+	"github.com/oNaiPs/go-generate-fast/src/plugins"
+	"github.com/oNaiPs/go-generate-fast/src/utils/fs"
+)
+
+// the key root, in the words and extra data of keys
+const keyRootVar = "${root}"
+
+// keyVersion is bumped when the way keys are computed or entries are stored
+// changes, so that older entries are not used anymore
+const keyVersion = "2"
+
+// keyRoot returns the directory the paths in the cache key of a directive are
+// relative to, so that the same checkout in different places gets the same
+// keys: the configured key root, or else the root of the module of the
+// directive, or else its dir.
+func keyRoot(opts plugins.GenerateOpts) string {
+	if config.Get().KeyRoot != "" {
+		return config.Get().KeyRoot
+	}
+	if goMod := fs.FindGoMod(opts.Dir()); goMod != "" {
+		return filepath.Dir(goMod)
+	}
+	return opts.Dir()
+}
+
+// keyPath returns a path relative to the command dir as relative to the key
+// root. Returns false when it is outside of the key root, with the absolute
+// path.
+func keyPath(opts plugins.GenerateOpts, root string, file string) (string, bool) {
+	absPath := fs.ResolvePath(opts.Dir(), file)
+
+	rel, err := filepath.Rel(root, absPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return filepath.ToSlash(absPath), false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// keyOutputFiles returns the paths of the output files for the cache key.
+// Outputs outside of the key root keep their absolute path, as they are
+// written there.
+func keyOutputFiles(opts plugins.GenerateOpts, root string, files []string) []string {
+	paths := make([]string, len(files))
+	for i, file := range files {
+		paths[i], _ = keyPath(opts, root, file)
+	}
+	return paths
+}
+
+// keyInputFiles returns a component per input file for the cache key, with
+// its path and hash. Files outside of the key root, like system includes, are
+// identified by their hash only.
+func keyInputFiles(opts plugins.GenerateOpts, root string, files []string) (KeyManifest, error) {
+	hashes, err := hashInputFiles(opts, files)
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(KeyManifest, len(files))
+	for i, file := range files {
+		p, ok := keyPath(opts, root, file)
+		if !ok {
+			p = ""
+		}
+		m[i] = KeyComponent{Kind: "input", Name: p, Value: hashes[i]}
+	}
+
+	// the order of the files outside of the root depends on their relative paths
+	slices.SortFunc(m, func(a, b KeyComponent) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.Value, b.Value))
+	})
+	return m, nil
+}
+
+// keyInputDirs returns the components of the input dirs for the cache key:
+// the dir and its rules, then a component per file with its path and hash, so
+// that added and removed files change the key.
+func keyInputDirs(opts plugins.GenerateOpts, root string, inputDirs []plugins.InputDir) (KeyManifest, error) {
+	var m KeyManifest
+	for _, inputDir := range inputDirs {
+		files, err := listInputDir(opts, inputDir)
+		if err != nil {
+			return nil, err
+		}
+
+		paths := make([]string, len(files))
+		for i, file := range files {
+			paths[i] = filepath.Join(inputDir.Path, filepath.FromSlash(file))
+		}
+		hashes, err := hashInputFiles(opts, paths)
+		if err != nil {
+			return nil, err
+		}
+
+		// files are named from the dir, even outside of the key root
+		dir, _ := keyPath(opts, root, inputDir.Path)
+		m.add("input-dir", dir, fmt.Sprintf("include:%s exclude:%s",
+			strings.Join(inputDir.Include, ","), strings.Join(inputDir.Exclude, ",")))
+		for i, file := range files {
+			m.add("input", path.Join(dir, file), hashes[i])
+		}
+	}
+	return m, nil
+}
+
+// keyInputPatterns returns the components of the input patterns for the cache
+// key: the pattern, then the files it matches.
+func keyInputPatterns(opts plugins.GenerateOpts, root string, patterns []string) (KeyManifest, error) {
+	var m KeyManifest
+	for _, pattern := range patterns {
+		matches, err := globFiles(opts.Dir(), pattern, doublestar.WithFilesOnly())
+		if err != nil {
+			return nil, fmt.Errorf("cannot match input pattern '%s': %w", pattern, err)
+		}
+
+		files, err := keyInputFiles(opts, root, matches)
+		if err != nil {
+			return nil, err
+		}
+		m.add("input-pattern", relocate(root, pattern), "")
+		m = append(m, files...)
+	}
+	return m, nil
+}
+
+// keyModules returns the components of the modules a generator is built
+// from, given as "path@version h1:hash".
+func keyModules(modules []string) KeyManifest {
+	var m KeyManifest
+	for _, module := range modules {
+		modPath, version, _ := strings.Cut(module, "@")
+		m.add("module", modPath, version)
+	}
+	return m
+}
+
+// relocate replaces the key root in s, for the strings of the key that may
+// contain absolute paths. The root is only replaced where it ends a path
+// element, so that /src/app2 is kept with a root of /src/app. A root that is
+// the filesystem root is never replaced.
+func relocate(root string, s string) string {
+	if root == "" || filepath.Dir(root) == root {
+		return s
+	}
+
+	var b strings.Builder
+	for {
+		i := strings.Index(s, root)
+		if i < 0 {
+			break
+		}
+		end := i + len(root)
+		b.WriteString(s[:i])
+		if end == len(s) || s[end] == '/' || s[end] == filepath.Separator {
+			b.WriteString(keyRootVar)
+		} else {
+			b.WriteString(root)
+		}
+		s = s[end:]
+	}
+	b.WriteString(s)
+	return b.String()
+}
+
+func relocateAll(root string, elements []string) []string {
+	relocated := make([]string, len(elements))
+	for i, s := range elements {
+		relocated[i] = relocate(root, s)
+	}
+	return relocated
+}
@@ -0,0 +1,224 @@
+package cache
+
+import (
+	"os"
+	"os/exec"
+	"path"
+	"testing"
+
+	"github.com/oNaiPs/go-generate-fast/src/core/config"
+	"github.com/oNaiPs/go-generate-fast/src/core/gitindex"
+	"github.com/oNaiPs/go-generate-fast/src/plugins"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCheckout writes the same module on a new dir, and returns the options
+// of a directive of its pkg dir.
+func writeCheckout(t *testing.T, include string) plugins.GenerateOpts {
+	t.Helper()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":          "module example.com/mod\n",
+		"proto/a.proto":   "syntax = \"proto3\";\n",
+		"pkg/gen.go":      "package pkg\n",
+		"pkg/local.proto": "syntax = \"proto3\";\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.MkdirAll(path.Dir(path.Join(dir, name)), 0700))
+		require.NoError(t, os.WriteFile(path.Join(dir, name), []byte(content), 0600))
+	}
+
+	return plugins.GenerateOpts{
+		Path:           path.Join(dir, "pkg", "gen.go"),
+		Words:          []string{"protoc", "-I", path.Join(dir, "proto"), "-I", include, "local.proto"},
+		ExecutableName: "go",
+	}
+}
+
+func TestRelocatableKey(t *testing.T) {
+	// include dir outside of the checkouts
+	include := path.Join(t.TempDir(), "system.proto")
+	require.NoError(t, os.WriteFile(include, []byte("system"), 0600))
+
+	opts1 := writeCheckout(t, path.Dir(include))
+	opts2 := writeCheckout(t, path.Dir(include))
+	ioFiles := func(opts plugins.GenerateOpts) plugins.InputOutputFiles {
+		return plugins.InputOutputFiles{
+			InputFiles:  []string{"local.proto", "../proto/a.proto", include},
+			OutputFiles: []string{"local.pb.go", path.Join(opts.Dir(), "..", "proto", "a.pb.go")},
+		}
+	}
+
+	dir1, err := calculateCacheDirectoryFromInputData(opts1, ioFiles(opts1))
+	assert.NoError(t, err)
+	dir2, err := calculateCacheDirectoryFromInputData(opts2, ioFiles(opts2))
+	assert.NoError(t, err)
+	assert.Equal(t, dir1, dir2, "The same checkout in another dir should produce the same cache directory")
+
+	// files outside of the checkout are identified by content
+	require.NoError(t, os.WriteFile(include, []byte("changed"), 0600))
+	dir3, err := calculateCacheDirectoryFromInputData(opts2, ioFiles(opts2))
+	assert.NoError(t, err)
+	assert.NotEqual(t, dir2, dir3)
+
+	// other dir of the checkout, with the same relative paths
+	require.NoError(t, os.WriteFile(path.Join(path.Dir(opts2.Dir()), "proto", "local.proto"), []byte("syntax = \"proto3\";\n"), 0600))
+	opts2.Path = path.Join(path.Dir(opts2.Dir()), "proto", "gen.go")
+	dir4, err := calculateCacheDirectoryFromInputData(opts2, plugins.InputOutputFiles{InputFiles: []string{"local.proto"}})
+	assert.NoError(t, err)
+	opts2.Path = path.Join(path.Dir(opts2.Dir()), "pkg", "gen.go")
+	dir5, err := calculateCacheDirectoryFromInputData(opts2, plugins.InputOutputFiles{InputFiles: []string{"local.proto"}})
+	assert.NoError(t, err)
+	assert.NotEqual(t, dir4, dir5)
+}
+
+func TestKeyRoot(t *testing.T) {
+	opts := writeCheckout(t, "/usr/include")
+	root := path.Dir(opts.Dir())
+	assert.Equal(t, root, keyRoot(opts))
+
+	oldKeyRoot := config.Get().KeyRoot
+	config.Get().KeyRoot = path.Dir(root)
+	t.Cleanup(func() {
+		config.Get().KeyRoot = oldKeyRoot
+	})
+	assert.Equal(t, path.Dir(root), keyRoot(opts))
+
+	p, ok := keyPath(opts, root, "../proto/a.proto")
+	assert.True(t, ok)
+	assert.Equal(t, "proto/a.proto", p)
+
+	p, ok = keyPath(opts, root, "/usr/include/a.proto")
+	assert.False(t, ok)
+	assert.Equal(t, "/usr/include/a.proto", p)
+
+	assert.Equal(t, []string{"-I", "${root}/proto"}, relocateAll(root, []string{"-I", root + "/proto"}))
+}
+
+func TestRelocate(t *testing.T) {
+	assert.Equal(t, "${root}", relocate("/src/app", "/src/app"))
+	assert.Equal(t, "-I${root}/x:${root}/y", relocate("/src/app", "-I/src/app/x:/src/app/y"))
+	assert.Equal(t, "/src/app2/x", relocate("/src/app", "/src/app2/x"))
+	assert.Equal(t, "/src/app2/x:${root}/x", relocate("/src/app", "/src/app2/x:/src/app/x"))
+	assert.Equal(t, "/src/app/x", relocate("/", "/src/app/x"))
+}
+
+func TestInputDirKey(t *testing.T) {
+	opts := writeCheckout(t, "/usr/include")
+	ioFiles := plugins.InputOutputFiles{
+		InputDirs: []plugins.InputDir{{Path: "../proto", Include: []string{"**/*.proto"}}},
+	}
+
+	dir1, err := calculateCacheDirectoryFromInputData(opts, ioFiles)
+	require.NoError(t, err)
+
+	// files that are not included do not change the key
+	protoDir := path.Join(path.Dir(opts.Dir()), "proto")
+	require.NoError(t, os.WriteFile(path.Join(protoDir, "README"), []byte("readme"), 0600))
+	dir2, err := calculateCacheDirectoryFromInputData(opts, ioFiles)
+	require.NoError(t, err)
+	assert.Equal(t, dir1, dir2)
+
+	// added files do
+	require.NoError(t, os.MkdirAll(path.Join(protoDir, "sub"), 0700))
+	require.NoError(t, os.WriteFile(path.Join(protoDir, "sub", "b.proto"), []byte("b"), 0600))
+	dir3, err := calculateCacheDirectoryFromInputData(opts, ioFiles)
+	require.NoError(t, err)
+	assert.NotEqual(t, dir2, dir3)
+
+	// unless excluded
+	ioFiles.InputDirs[0].Exclude = []string{"sub"}
+	dir4, err := calculateCacheDirectoryFromInputData(opts, ioFiles)
+	require.NoError(t, err)
+	assert.NotEqual(t, dir3, dir4)
+	ioFiles.InputDirs[0].Exclude = nil
+
+	// and so do removed ones
+	require.NoError(t, os.Remove(path.Join(protoDir, "sub", "b.proto")))
+	dir5, err := calculateCacheDirectoryFromInputData(opts, ioFiles)
+	require.NoError(t, err)
+	assert.Equal(t, dir2, dir5)
+
+	require.NoError(t, os.RemoveAll(protoDir))
+	dir6, err := calculateCacheDirectoryFromInputData(opts, ioFiles)
+	require.NoError(t, err)
+	assert.NotEqual(t, dir5, dir6)
+}
+
+func TestInputPatternKey(t *testing.T) {
+	opts := writeCheckout(t, "/usr/include")
+	ioFiles := plugins.InputOutputFiles{InputPatterns: []string{"*.proto"}}
+
+	dir1, err := calculateCacheDirectoryFromInputData(opts, ioFiles)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(path.Join(opts.Dir(), "other.proto"), []byte("other"), 0600))
+	dir2, err := calculateCacheDirectoryFromInputData(opts, ioFiles)
+	require.NoError(t, err)
+	assert.NotEqual(t, dir1, dir2)
+
+	require.NoError(t, os.Remove(path.Join(opts.Dir(), "other.proto")))
+	dir3, err := calculateCacheDirectoryFromInputData(opts, ioFiles)
+	require.NoError(t, err)
+	assert.Equal(t, dir1, dir3)
+
+	// a pattern without matches is part of the key too
+	ioFiles.InputPatterns = append(ioFiles.InputPatterns, "*.missing")
+	dir4, err := calculateCacheDirectoryFromInputData(opts, ioFiles)
+	require.NoError(t, err)
+	assert.NotEqual(t, dir3, dir4)
+}
+
+func TestGitHashModeKey(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	gitindex.TrustRecentChanges(t)
+
+	oldHashMode := config.Get().HashMode
+	config.Get().HashMode = config.HashModeGit
+	t.Cleanup(func() {
+		config.Get().HashMode = oldHashMode
+	})
+
+	git := func(opts plugins.GenerateOpts, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", path.Dir(opts.Dir()), "-c", "user.name=test", "-c", "user.email=test@test"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	ioFiles := plugins.InputOutputFiles{InputFiles: []string{"local.proto", "../proto/a.proto"}}
+
+	// committed, identified by the index
+	clean := writeCheckout(t, "/usr/include")
+	git(clean, "init", "-q")
+	git(clean, "add", ".")
+	git(clean, "commit", "-q", "-m", "init")
+	ids, _ := gitindex.BlobIDs(clean.Dir(), ioFiles.InputFiles)
+	require.Len(t, ids, 2)
+	cleanDir, err := calculateCacheDirectoryFromInputData(clean, ioFiles)
+	require.NoError(t, err)
+
+	// the same content, not committed yet
+	untracked := writeCheckout(t, "/usr/include")
+	git(untracked, "init", "-q")
+	untrackedDir, err := calculateCacheDirectoryFromInputData(untracked, ioFiles)
+	require.NoError(t, err)
+	assert.Equal(t, cleanDir, untrackedDir)
+
+	// the same content, differing from the committed one
+	dirty := writeCheckout(t, "/usr/include")
+	localProto := path.Join(dirty.Dir(), "local.proto")
+	content, err := os.ReadFile(localProto)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(localProto, []byte("committed"), 0600))
+	git(dirty, "init", "-q")
+	git(dirty, "add", ".")
+	git(dirty, "commit", "-q", "-m", "init")
+	require.NoError(t, os.WriteFile(localProto, content, 0600))
+	dirtyDir, err := calculateCacheDirectoryFromInputData(dirty, ioFiles)
+	require.NoError(t, err)
+	assert.Equal(t, cleanDir, dirtyDir)
+}
@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/oNaiPs/go-generate-fast/src/core/config"
+	"github.com/oNaiPs/go-generate-fast/src/utils/flock"
+	"github.com/oNaiPs/go-generate-fast/src/utils/fs"
+)
+
+const (
+	locksDirName   = "locks"
+	gcLockFileName = "gc.lock"
+)
+
+// entryLockPath returns the lock file of an entry. Entries are locked in
+// stripes, by the <1 hex char>/<2 hex chars> dirs they are stored in, so that
+// the number of lock files stays bounded.
+func entryLockPath(cacheHitDir string) (string, error) {
+	key := cacheKey(cacheHitDir)
+	if key == "" {
+		// not inside the cache dir, lock the entry on its own
+		return cacheHitDir + ".lock", nil
+	}
+
+	stripe := strings.ReplaceAll(filepath.ToSlash(filepath.Dir(key)), "/", "")
+	return locksFilePath(stripe + ".lock")
+}
+
+func locksFilePath(name string) (string, error) {
+	locksDir := filepath.Join(config.Get().CacheDir, locksDirName)
+	err := fs.MkdirAll(locksDir, config.Get().DirPerm())
+	if err != nil {
+		return "", fmt.Errorf("cannot create locks dir: %w", err)
+	}
+	return filepath.Join(locksDir, name), nil
+}
+
+// lockEntry blocks until the entry is locked. Entries are read under a shared
+// lock, and committed or removed under an exclusive one.
+func lockEntry(cacheHitDir string, mode flock.Mode) (*flock.Lock, error) {
+	lockPath, err := entryLockPath(cacheHitDir)
+	if err != nil {
+		return nil, err
+	}
+
+	lock, err := flock.Acquire(lockPath, config.Get().FilePerm(), mode)
+	if err != nil {
+		return nil, fmt.Errorf("cannot lock cache entry: %w", err)
+	}
+	return lock, nil
+}
+
+// tryLockEntry locks the entry, or returns flock.ErrLocked when it is in use.
+func tryLockEntry(cacheHitDir string, mode flock.Mode) (*flock.Lock, error) {
+	lockPath, err := entryLockPath(cacheHitDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return flock.TryAcquire(lockPath, config.Get().FilePerm(), mode)
+}
@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/oNaiPs/go-generate-fast/src/core/config"
+	"github.com/oNaiPs/go-generate-fast/src/plugins"
+	"github.com/oNaiPs/go-generate-fast/src/utils/flock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrentSaveAndRestore(t *testing.T) {
+	setTempCacheDir(t)
+
+	const writers = 16
+	key := "a/bc/def"
+	output := path.Join(t.TempDir(), "out.txt")
+	require.NoError(t, os.WriteFile(output, []byte("some-content"), 0600))
+
+	verifyRes := VerifyResult{
+		CacheHitDir: path.Join(config.Get().CacheDir, key),
+		CacheKey:    key,
+		IoFiles: plugins.InputOutputFiles{
+			OutputFiles: []string{output},
+		},
+	}
+
+	// every writer saves the same entry while the others restore it
+	var wg sync.WaitGroup
+	for range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, Save(verifyRes))
+			_, err := Restore(verifyRes)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	entries, err := ListEntries()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.True(t, entries[0].Complete)
+
+	matches, err := filepath.Glob(path.Join(config.Get().CacheDir, "a", "bc", stagingPrefix+"*"))
+	assert.NoError(t, err)
+	assert.Empty(t, matches, "no staging dirs shall be left behind")
+}
+
+func TestGCKeepsEntriesInUse(t *testing.T) {
+	setTempCacheDir(t)
+
+	verifyRes := saveTestEntry(t, "a/bc/aaa", 10, time.Now().Add(-48*time.Hour))
+
+	lock, err := lockEntry(verifyRes.CacheHitDir, flock.Shared)
+	require.NoError(t, err)
+
+	result, err := GC(GCOptions{MaxAge: 24 * time.Hour})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.Removed)
+	assert.Equal(t, 1, result.Remaining)
+	assert.DirExists(t, verifyRes.CacheHitDir)
+
+	assert.NoError(t, lock.Release())
+
+	result, err = GC(GCOptions{MaxAge: 24 * time.Hour})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Removed)
+	assert.NoDirExists(t, verifyRes.CacheHitDir)
+}
+
+func TestSharedCachePerm(t *testing.T) {
+	setTempCacheDir(t)
+
+	config.Get().Shared = true
+	t.Cleanup(func() { config.Get().Shared = false })
+
+	verifyRes := saveTestEntry(t, "a/bc/def", 10, time.Now())
+
+	for _, dir := range []string{path.Join(config.Get().CacheDir, "a"), verifyRes.CacheHitDir} {
+		info, err := os.Stat(dir)
+		require.NoError(t, err)
+		assert.Equal(t, config.Get().DirPerm(), info.Mode()&(os.ModePerm|os.ModeSetgid), dir)
+	}
+
+	dirEntries, err := os.ReadDir(verifyRes.CacheHitDir)
+	require.NoError(t, err)
+	for _, dirEntry := range dirEntries {
+		info, err := dirEntry.Info()
+		require.NoError(t, err)
+		assert.Equal(t, config.Get().FilePerm(), info.Mode().Perm(), dirEntry.Name())
+	}
+
+	lockPath, err := entryLockPath(verifyRes.CacheHitDir)
+	require.NoError(t, err)
+	assert.Equal(t, path.Join(config.Get().CacheDir, locksDirName, "abc.lock"), lockPath)
+	info, err := os.Stat(lockPath)
+	require.NoError(t, err)
+	assert.Equal(t, config.Get().FilePerm(), info.Mode().Perm())
+}
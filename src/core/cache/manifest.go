@@ -0,0 +1,116 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/oNaiPs/go-generate-fast/src/utils/hash"
+)
+
+// KeyComponent is one of the things the cache key of a directive is computed
+// from, like an input file and its hash, or a word of the command.
+type KeyComponent struct {
+	Kind  string
+	Name  string `json:",omitempty"`
+	Value string `json:",omitempty"`
+}
+
+// KeyManifest lists the components of a cache key, in order. It is saved with
+// the entry, to explain why a directive missed the cache later on.
+type KeyManifest []KeyComponent
+
+func (m *KeyManifest) add(kind string, name string, value string) {
+	*m = append(*m, KeyComponent{Kind: kind, Name: name, Value: value})
+}
+
+// hash returns the cache key computed from the components.
+func (m KeyManifest) hash() (string, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return "", fmt.Errorf("cannot marshal key manifest: %w", err)
+	}
+	return hash.HashString(string(data))
+}
+
+const (
+	ComponentAdded   = "added"
+	ComponentRemoved = "removed"
+	ComponentChanged = "changed"
+)
+
+// KeyChange is a component that differs between two key manifests.
+type KeyChange struct {
+	// ComponentAdded, ComponentRemoved or ComponentChanged
+	Change string
+	Kind   string
+	Name   string
+	// value in the previous manifest, empty when added
+	Old string
+	// value in the current manifest, empty when removed
+	New string
+}
+
+func (c KeyChange) String() string {
+	component := c.Kind
+	if c.Name != "" {
+		component += " " + c.Name
+	}
+
+	switch c.Change {
+	case ComponentAdded:
+		return fmt.Sprintf("%s: added %s", component, c.New)
+	case ComponentRemoved:
+		return fmt.Sprintf("%s: removed %s", component, c.Old)
+	default:
+		return fmt.Sprintf("%s: %s -> %s", component, c.Old, c.New)
+	}
+}
+
+type componentID struct {
+	kind string
+	name string
+}
+
+// DiffManifests returns the components that changed from old to new. The
+// components are matched by kind and name; the ones sharing a kind and name,
+// like files outside of the key root, are matched by value.
+func DiffManifests(old KeyManifest, new KeyManifest) []KeyChange {
+	var ids []componentID
+	oldValues := map[componentID][]string{}
+	newValues := map[componentID][]string{}
+	collect := func(m KeyManifest, values map[componentID][]string) {
+		for _, c := range m {
+			id := componentID{c.Kind, c.Name}
+			if oldValues[id] == nil && newValues[id] == nil {
+				ids = append(ids, id)
+			}
+			values[id] = append(values[id], c.Value)
+		}
+	}
+	collect(new, newValues)
+	collect(old, oldValues)
+
+	var changes []KeyChange
+	for _, id := range ids {
+		oldValue, newValue := oldValues[id], newValues[id]
+		if len(oldValue) == 1 && len(newValue) == 1 {
+			if oldValue[0] != newValue[0] {
+				changes = append(changes, KeyChange{Change: ComponentChanged, Kind: id.kind, Name: id.name, Old: oldValue[0], New: newValue[0]})
+			}
+			continue
+		}
+
+		for _, v := range oldValue {
+			if !slices.Contains(newValue, v) {
+				changes = append(changes, KeyChange{Change: ComponentRemoved, Kind: id.kind, Name: id.name, Old: v})
+			}
+		}
+		for _, v := range newValue {
+			if !slices.Contains(oldValue, v) {
+				changes = append(changes, KeyChange{Change: ComponentAdded, Kind: id.kind, Name: id.name, New: v})
+			}
+		}
+	}
+	return changes
+}
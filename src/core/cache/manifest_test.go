@@ -0,0 +1,44 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffManifests(t *testing.T) {
+	old := KeyManifest{
+		{Kind: "arg", Name: "0", Value: "stringer"},
+		{Kind: "arg", Name: "1", Value: "-type=A"},
+		{Kind: "input", Name: "a.go", Value: "hash-a"},
+		{Kind: "input", Name: "b.go", Value: "hash-b"},
+		{Kind: "input", Value: "hash-outside-1"},
+		{Kind: "input", Value: "hash-outside-2"},
+	}
+	new := KeyManifest{
+		{Kind: "arg", Name: "0", Value: "stringer"},
+		{Kind: "arg", Name: "1", Value: "-type=B"},
+		{Kind: "input", Name: "a.go", Value: "hash-a"},
+		{Kind: "input", Name: "c.go", Value: "hash-c"},
+		{Kind: "input", Value: "hash-outside-2"},
+		{Kind: "input", Value: "hash-outside-3"},
+	}
+
+	assert.Empty(t, DiffManifests(old, old))
+	assert.Equal(t, []KeyChange{
+		{Change: ComponentChanged, Kind: "arg", Name: "1", Old: "-type=A", New: "-type=B"},
+		{Change: ComponentAdded, Kind: "input", Name: "c.go", New: "hash-c"},
+		{Change: ComponentRemoved, Kind: "input", Old: "hash-outside-1"},
+		{Change: ComponentAdded, Kind: "input", New: "hash-outside-3"},
+		{Change: ComponentRemoved, Kind: "input", Name: "b.go", Old: "hash-b"},
+	}, DiffManifests(old, new))
+}
+
+func TestKeyChangeString(t *testing.T) {
+	assert.Equal(t, "arg 1: -type=A -> -type=B",
+		KeyChange{Change: ComponentChanged, Kind: "arg", Name: "1", Old: "-type=A", New: "-type=B"}.String())
+	assert.Equal(t, "input c.go: added hash-c",
+		KeyChange{Change: ComponentAdded, Kind: "input", Name: "c.go", New: "hash-c"}.String())
+	assert.Equal(t, "salt: removed bad-release",
+		KeyChange{Change: ComponentRemoved, Kind: "salt", Old: "bad-release"}.String())
+}
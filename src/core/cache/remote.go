@@ -0,0 +1,221 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/oNaiPs/go-generate-fast/src/core/config"
+	"github.com/oNaiPs/go-generate-fast/src/core/storage"
+	"go.uber.org/zap"
+)
+
+var remote struct {
+	sync.Mutex
+	url         string
+	storage     storage.Storage
+	unreachable bool
+}
+
+// getRemote returns the configured remote storage, or nil when there is none
+// or when it was found to be unreachable during this run.
+func getRemote() storage.Storage {
+	remote.Lock()
+	defer remote.Unlock()
+
+	url := config.Get().RemoteURL
+	if url == "" {
+		return nil
+	}
+
+	if remote.url != url {
+		remote.url = url
+		remote.unreachable = false
+		s, err := storage.New(url, storage.Options{
+			Timeout: config.Get().RemoteTimeout,
+			Token:   config.Get().RemoteToken,
+		})
+		if err != nil {
+			zap.S().Errorf("cannot use remote cache: %s", err)
+			remote.unreachable = true
+		}
+		remote.storage = s
+	}
+
+	if remote.unreachable {
+		return nil
+	}
+	return remote.storage
+}
+
+// disableRemote stops using the remote storage for the rest of the run, so
+// that an unreachable server does not slow down every directive.
+func disableRemote(err error) {
+	remote.Lock()
+	defer remote.Unlock()
+
+	if !remote.unreachable {
+		zap.S().Warnf("remote cache unreachable, using local cache only: %s", err)
+	}
+	remote.unreachable = true
+}
+
+// cacheKey returns the key of a cache hit dir, i.e. its path relative to the cache dir.
+// Returns an empty string when the dir is not inside the cache dir.
+func cacheKey(cacheHitDir string) string {
+	key, err := filepath.Rel(config.Get().CacheDir, cacheHitDir)
+	if err != nil || key == "." || strings.HasPrefix(key, "..") {
+		return ""
+	}
+	return filepath.ToSlash(key)
+}
+
+func existsRemote(key string) bool {
+	r := getRemote()
+	if r == nil || key == "" {
+		return false
+	}
+
+	exists, err := r.Exists(key, completeFileName)
+	if err != nil {
+		disableRemote(err)
+		return false
+	}
+
+	zap.S().Debugf("Remote cache hit for %s: %t", key, exists)
+	return exists
+}
+
+// fetchRemote downloads an entry from the remote storage into the local cache.
+func fetchRemote(key string, cacheHitDir string) error {
+	r := getRemote()
+	if r == nil {
+		return errors.New("remote cache not available")
+	}
+
+	var configData bytes.Buffer
+	err := r.Get(key, configFileName, &configData)
+	if err != nil {
+		return fmt.Errorf("cannot download cache config: %w", err)
+	}
+
+	var cacheConfig CacheConfig
+	err = json.Unmarshal(configData.Bytes(), &cacheConfig)
+	if err != nil {
+		return fmt.Errorf("cannot unmarshal cache config file: %w", err)
+	}
+
+	stagingDir, err := createStagingDir(cacheHitDir)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.RemoveAll(stagingDir) }()
+
+	err = fetchRemoteFiles(r, key, cacheConfig)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(GetConfigFilePath(stagingDir), configData.Bytes(), 0600)
+	if err != nil {
+		return fmt.Errorf("cannot write cache config file: %w", err)
+	}
+
+	err = commitEntry(stagingDir, cacheHitDir)
+	if err != nil {
+		return err
+	}
+
+	zap.S().Debugf("Fetched remote cache entry %s", key)
+
+	return nil
+}
+
+func fetchRemoteFiles(r storage.Storage, key string, cacheConfig CacheConfig) error {
+	for _, file := range cacheConfig.OutputFiles {
+		// shared with a local entry, no need to download it
+		if useBlob(file.BlobName()) {
+			continue
+		}
+
+		err := fetchRemoteBlob(r, key, file)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func fetchRemoteBlob(r storage.Storage, key string, file CacheConfigOutputFileInfo) error {
+	tmpFile, err := createBlobTempFile()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmpFile) }()
+
+	f, err := os.OpenFile(tmpFile, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("cannot create cached file %s: %w", file.Path, err)
+	}
+
+	err = r.Get(key, file.BlobName(), f)
+	_ = f.Close()
+	if err != nil {
+		return fmt.Errorf("cannot download cached file %s: %w", file.Path, err)
+	}
+
+	// the hash is the one of the uncompressed content
+	fileHash, err := readBlob(tmpFile, io.Discard, file.Compression)
+	if err != nil {
+		return fmt.Errorf("cannot hash downloaded file %s: %w", file.Path, err)
+	}
+	if fileHash != file.Hash {
+		return fmt.Errorf("downloaded file %s hash is different, corruption", file.Path)
+	}
+
+	return commitBlob(tmpFile, file.BlobName())
+}
+
+// uploadRemote uploads a saved local entry to the remote storage.
+func uploadRemote(key string, cacheHitDir string, cacheConfig CacheConfig) {
+	r := getRemote()
+	if r == nil || key == "" {
+		return
+	}
+
+	files := map[string]string{}
+	names := []string{}
+	for _, file := range cacheConfig.OutputFiles {
+		files[file.BlobName()] = entryBlobPath(cacheHitDir, file.BlobName())
+		names = append(names, file.BlobName())
+	}
+	// the complete marker goes last, so that other machines never see incomplete entries
+	for _, name := range []string{configFileName, completeFileName} {
+		files[name] = path.Join(cacheHitDir, name)
+		names = append(names, name)
+	}
+
+	for _, name := range names {
+		f, err := os.Open(files[name])
+		if err != nil {
+			zap.S().Warnf("cannot upload cache entry %s: %s", key, err)
+			return
+		}
+
+		err = r.Put(key, name, f)
+		_ = f.Close()
+		if err != nil {
+			zap.S().Warnf("cannot upload cache entry %s: %s", key, err)
+			return
+		}
+	}
+
+	zap.S().Debugf("Uploaded cache entry %s to remote", key)
+}
@@ -0,0 +1,127 @@
+package cache
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/oNaiPs/go-generate-fast/src/core/config"
+	"github.com/oNaiPs/go-generate-fast/src/core/storage"
+	"github.com/oNaiPs/go-generate-fast/src/plugins"
+	util_test "github.com/oNaiPs/go-generate-fast/src/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func setRemoteURL(t *testing.T, url string) {
+	t.Helper()
+
+	setTempCacheDir(t)
+
+	oldURL := config.Get().RemoteURL
+	config.Get().RemoteURL = url
+	t.Cleanup(func() {
+		config.Get().RemoteURL = oldURL
+	})
+}
+
+func TestCacheKey(t *testing.T) {
+	setRemoteURL(t, "")
+
+	assert.Equal(t, "a/bc/def", cacheKey(path.Join(config.Get().CacheDir, "a", "bc", "def")))
+	assert.Equal(t, "", cacheKey(config.Get().CacheDir))
+	assert.Equal(t, "", cacheKey(t.TempDir()))
+}
+
+func TestRemoteSaveAndRestore(t *testing.T) {
+	remoteDir := t.TempDir()
+	setRemoteURL(t, "file://"+remoteDir)
+
+	file1 := util_test.WriteTempFile(t, "some-content")
+	file2 := util_test.WriteTempFile(t, "some-other-content")
+
+	key := "a/bc/def"
+	verifyRes := VerifyResult{
+		CacheHitDir: path.Join(config.Get().CacheDir, key),
+		CacheKey:    key,
+		IoFiles: plugins.InputOutputFiles{
+			OutputFiles: []string{file1.Name(), file2.Name()},
+		},
+	}
+
+	err := Save(verifyRes)
+	assert.NoError(t, err)
+	assert.FileExists(t, path.Join(remoteDir, key, configFileName))
+	assert.FileExists(t, path.Join(remoteDir, key, completeFileName))
+	assert.True(t, existsRemote(key))
+	assert.False(t, existsRemote("a/bc/other"))
+
+	cacheConfig, err := LoadConfig(verifyRes.CacheHitDir)
+	assert.NoError(t, err)
+	assert.FileExists(t, storage.BlobPath(remoteDir, cacheConfig.OutputFiles[0].Hash))
+
+	// simulate another machine, with an empty local cache and no outputs
+	assert.NoError(t, os.RemoveAll(verifyRes.CacheHitDir))
+	assert.NoError(t, os.RemoveAll(path.Join(config.Get().CacheDir, storage.BlobsDirName)))
+	assert.NoError(t, os.Remove(file1.Name()))
+	assert.NoError(t, os.Remove(file2.Name()))
+
+	verifyRes.RemoteHit = true
+	restored, err := Restore(verifyRes)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, restored)
+
+	assert.FileExists(t, GetConfigFilePath(verifyRes.CacheHitDir), "local cache shall be filled")
+	assert.True(t, isComplete(verifyRes.CacheHitDir))
+	data, err := os.ReadFile(file1.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, "some-content", string(data))
+	data, err = os.ReadFile(file2.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, "some-other-content", string(data))
+}
+
+func TestRemoteCorruptBlob(t *testing.T) {
+	remoteDir := t.TempDir()
+	setRemoteURL(t, "file://"+remoteDir)
+
+	output := util_test.WriteTempFile(t, "some-content")
+	key := "a/bc/def"
+	verifyRes := VerifyResult{
+		CacheHitDir: path.Join(config.Get().CacheDir, key),
+		CacheKey:    key,
+		IoFiles: plugins.InputOutputFiles{
+			OutputFiles: []string{output.Name()},
+		},
+	}
+	assert.NoError(t, Save(verifyRes))
+
+	cacheConfig, err := LoadConfig(verifyRes.CacheHitDir)
+	assert.NoError(t, err)
+	hash := cacheConfig.OutputFiles[0].Hash
+	assert.NoError(t, os.WriteFile(storage.BlobPath(remoteDir, hash), []byte("corrupted"), 0600))
+
+	assert.NoError(t, os.RemoveAll(verifyRes.CacheHitDir))
+	assert.NoError(t, os.RemoveAll(path.Join(config.Get().CacheDir, storage.BlobsDirName)))
+
+	err = fetchRemote(key, verifyRes.CacheHitDir)
+	assert.ErrorContains(t, err, "hash is different, corruption")
+	assert.NoDirExists(t, verifyRes.CacheHitDir)
+	assert.NoFileExists(t, blobPath(hash))
+}
+
+func TestRemoteMissingEntry(t *testing.T) {
+	setRemoteURL(t, "file://"+t.TempDir())
+
+	cacheHitDir := path.Join(config.Get().CacheDir, "a", "bc", "def")
+	err := fetchRemote("a/bc/def", cacheHitDir)
+	assert.ErrorContains(t, err, "cannot download cache config")
+	assert.NoDirExists(t, cacheHitDir)
+}
+
+func TestRemoteUnreachable(t *testing.T) {
+	setRemoteURL(t, "http://127.0.0.1:1")
+
+	assert.NotNil(t, getRemote())
+	assert.False(t, existsRemote("a/bc/def"))
+	assert.Nil(t, getRemote(), "unreachable remote shall be disabled for the rest of the run")
+}
@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"sort"
+	"time"
+)
+
+// EntryDetails is a cache entry along with its loaded config.
+type EntryDetails struct {
+	Entry
+	Config CacheConfig
+	// set when the config cannot be loaded
+	Error error
+}
+
+// Restored reports whether the entry was restored since it was saved.
+func (e EntryDetails) Restored() bool {
+	return !e.LastRestored.IsZero()
+}
+
+// Inspect returns all cache entries with their configs, least recently used first.
+func Inspect() ([]EntryDetails, error) {
+	entries, err := ListEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastUsed.Before(entries[j].LastUsed)
+	})
+
+	details := make([]EntryDetails, 0, len(entries))
+	for _, entry := range entries {
+		cacheConfig, err := LoadConfig(entry.Dir)
+		details = append(details, EntryDetails{
+			Entry:  entry,
+			Config: cacheConfig,
+			Error:  err,
+		})
+	}
+
+	return details, nil
+}
+
+type PluginStats struct {
+	Entries int
+	Size    int64
+}
+
+type Stats struct {
+	Entries int
+	// size on disk, blobs shared by several entries are counted once
+	TotalSize int64
+	// size saved by sharing identical output files between entries
+	SharedSize int64
+	// entries that were never restored since they were saved
+	NeverRestored int
+	// incomplete entries, or whose config cannot be loaded
+	Invalid    int
+	OldestUsed time.Time
+	NewestUsed time.Time
+	// stats grouped by plugin name, custom commands are grouped under an empty name
+	Plugins map[string]PluginStats
+}
+
+func ComputeStats(details []EntryDetails) Stats {
+	stats := Stats{Plugins: map[string]PluginStats{}}
+	blobs := map[string]bool{}
+
+	for _, entry := range details {
+		size := unreferencedSize(entry.Entry, blobs)
+		stats.Entries++
+		stats.TotalSize += size
+		stats.SharedSize += entry.Size - size
+		for name := range entry.Blobs {
+			blobs[name] = true
+		}
+
+		if stats.OldestUsed.IsZero() || entry.LastUsed.Before(stats.OldestUsed) {
+			stats.OldestUsed = entry.LastUsed
+		}
+		if entry.LastUsed.After(stats.NewestUsed) {
+			stats.NewestUsed = entry.LastUsed
+		}
+
+		if entry.Error != nil || !entry.Complete {
+			stats.Invalid++
+			continue
+		}
+		if !entry.Restored() {
+			stats.NeverRestored++
+		}
+
+		pluginStats := stats.Plugins[entry.Config.Plugin]
+		pluginStats.Entries++
+		pluginStats.Size += entry.Size
+		stats.Plugins[entry.Config.Plugin] = pluginStats
+	}
+
+	return stats
+}
@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/oNaiPs/go-generate-fast/src/core/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInspect(t *testing.T) {
+	setTempCacheDir(t)
+
+	now := time.Now().Truncate(time.Second)
+	saved := saveTestEntry(t, "a/bc/aaa", 10, now)
+	saveTestEntry(t, "b/cd/bbb", 10, now.Add(-time.Hour))
+	assert.NoError(t, os.MkdirAll(path.Join(config.Get().CacheDir, "c", "de", "ccc"), 0700))
+
+	details, err := Inspect()
+	assert.NoError(t, err)
+	assert.Len(t, details, 3)
+
+	// least recently used first, the incomplete entry was just created
+	assert.Equal(t, "b/cd/bbb", details[0].Key)
+	assert.Equal(t, "a/bc/aaa", details[1].Key)
+	assert.Equal(t, "c/de/ccc", details[2].Key)
+
+	assert.NoError(t, details[1].Error)
+	assert.Equal(t, saved.IoFiles.OutputFiles[0], details[1].Config.OutputFiles[0].Path)
+	assert.False(t, details[1].Config.CreatedAt.IsZero())
+	assert.True(t, details[1].Complete)
+	assert.False(t, details[2].Complete)
+	assert.ErrorContains(t, details[2].Error, "cannot read cache config file")
+}
+
+func TestComputeStats(t *testing.T) {
+	now := time.Now()
+	details := []EntryDetails{
+		{
+			Entry:  Entry{Key: "a/bc/aaa", Size: 100, Complete: true, LastUsed: now.Add(-time.Hour)},
+			Config: CacheConfig{Plugin: "stringer", CreatedAt: now.Add(-time.Hour)},
+		},
+		{
+			Entry:  Entry{Key: "a/bc/bbb", Size: 200, Complete: true, LastUsed: now, LastRestored: now},
+			Config: CacheConfig{Plugin: "stringer", CreatedAt: now.Add(-time.Hour)},
+		},
+		{
+			Entry:  Entry{Key: "a/bc/ccc", Size: 50, Complete: true, LastUsed: now.Add(-2 * time.Hour)},
+			Config: CacheConfig{CreatedAt: now.Add(-2 * time.Hour)},
+		},
+		{
+			Entry: Entry{Key: "a/bc/ddd", Size: 1, LastUsed: now.Add(-time.Minute)},
+			Error: os.ErrNotExist,
+		},
+	}
+
+	stats := ComputeStats(details)
+	assert.Equal(t, 4, stats.Entries)
+	assert.Equal(t, int64(351), stats.TotalSize)
+	assert.Equal(t, 2, stats.NeverRestored)
+	assert.Equal(t, 1, stats.Invalid)
+	assert.Equal(t, now.Add(-2*time.Hour), stats.OldestUsed)
+	assert.Equal(t, now, stats.NewestUsed)
+	assert.Equal(t, map[string]PluginStats{
+		"stringer": {Entries: 2, Size: 300},
+		"":         {Entries: 1, Size: 50},
+	}, stats.Plugins)
+}
@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var cacheCommandsMap = make(map[string]command)
+
+func init() {
+	register(command{
+		name:  "cache",
+		short: "Inspects and maintains the cache directory.",
+		run:   runCache,
+	})
+}
+
+func registerCacheCommand(cmd command) {
+	cacheCommandsMap[cmd.name] = cmd
+}
+
+func runCache(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing cache command, available: %s", cacheCommandNames())
+	}
+
+	cmd, ok := cacheCommandsMap[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown cache command %q, available: %s", args[0], cacheCommandNames())
+	}
+
+	return cmd.run(args[1:])
+}
+
+func cacheCommandNames() string {
+	names := []string{}
+	for name := range cacheCommandsMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
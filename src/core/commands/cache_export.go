@@ -0,0 +1,82 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/oNaiPs/go-generate-fast/src/core/cache"
+	"github.com/oNaiPs/go-generate-fast/src/utils/compress"
+	"github.com/oNaiPs/go-generate-fast/src/utils/str"
+)
+
+func init() {
+	registerCacheCommand(command{
+		name:  "export",
+		short: "Writes the cache entries and the files they reference to a tar archive.",
+		run:   runCacheExport,
+	})
+}
+
+func runCacheExport(args []string) error {
+	flagSet := newFlagSet("cache export", cacheCommandsMap["export"].short)
+	output := flagSet.String("o", "-", "archive file, gzip compressed when ending in .gz or .tgz, - for stdout")
+	since := flagSet.String("since", "", "only export the entries used within this time, e.g. 7d or 12h")
+	lastRun := flagSet.Bool("last-run", false, "only export the entries used by the last generate run")
+
+	err := flagSet.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	opts := cache.ExportOptions{}
+	if *since != "" && *lastRun {
+		return errors.New("-since and -last-run cannot be used together")
+	} else if *since != "" {
+		d, err := str.ParseDuration(*since)
+		if err != nil {
+			return err
+		}
+		opts.UsedSince = time.Now().Add(-d)
+	} else if *lastRun {
+		opts.UsedSince, err = cache.LastRunStart()
+		if err != nil {
+			return err
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = f.Close() }()
+		w = f
+	}
+
+	codec := compress.None
+	if strings.HasSuffix(*output, ".gz") || strings.HasSuffix(*output, ".tgz") {
+		codec = compress.Gzip
+	}
+	cw, err := compress.NewWriter(w, codec)
+	if err != nil {
+		return err
+	}
+
+	result, err := cache.Export(cw, opts)
+	if err != nil {
+		return err
+	}
+	err = cw.Close()
+	if err != nil {
+		return err
+	}
+
+	// stdout may hold the archive
+	_, _ = fmt.Fprintf(os.Stderr, "exported %d entries, %d blobs\n", result.Entries, result.Blobs)
+	return nil
+}
@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/oNaiPs/go-generate-fast/src/core/cache"
+	"github.com/oNaiPs/go-generate-fast/src/core/config"
+	"github.com/oNaiPs/go-generate-fast/src/utils/str"
+)
+
+func init() {
+	registerCacheCommand(command{
+		name:  "gc",
+		short: "Removes least recently used cache entries until the cache fits the size and age limits.",
+		run:   runCacheGC,
+	})
+}
+
+func runCacheGC(args []string) error {
+	flagSet := newFlagSet("cache gc", cacheCommandsMap["gc"].short)
+	maxSize := flagSet.String("max-size", "", "maximum cache size, e.g. 500MB (default $GO_GENERATE_FAST_MAX_SIZE)")
+	maxAge := flagSet.String("max-age", "", "maximum time since last use, e.g. 30d or 72h (default $GO_GENERATE_FAST_MAX_AGE)")
+	dryRun := flagSet.Bool("dry-run", false, "only print the entries that would be removed")
+
+	err := flagSet.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	opts := cache.GCOptions{
+		MaxSize: config.Get().MaxSize,
+		MaxAge:  config.Get().MaxAge,
+		DryRun:  *dryRun,
+	}
+	if *maxSize != "" {
+		opts.MaxSize, err = str.ParseSize(*maxSize)
+		if err != nil {
+			return err
+		}
+	}
+	if *maxAge != "" {
+		opts.MaxAge, err = str.ParseDuration(*maxAge)
+		if err != nil {
+			return err
+		}
+	}
+	if opts.MaxSize == 0 && opts.MaxAge == 0 {
+		return errors.New("no limits set, use -max-size or -max-age")
+	}
+
+	result, err := cache.GC(opts)
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		for _, entry := range result.RemovedEntries {
+			fmt.Printf("%s\t%s\t%s\n", entry.Key, str.FormatSize(entry.Size), entry.LastUsed.Format(time.RFC3339))
+		}
+		fmt.Print("dry run: ")
+	}
+	fmt.Println(result)
+
+	return nil
+}
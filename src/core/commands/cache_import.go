@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/oNaiPs/go-generate-fast/src/core/cache"
+	"github.com/oNaiPs/go-generate-fast/src/utils/compress"
+)
+
+func init() {
+	registerCacheCommand(command{
+		name:  "import",
+		short: "Merges the entries of a tar archive written by cache export into the cache.",
+		run:   runCacheImport,
+	})
+}
+
+// gzipMagic starts every gzip stream.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+func runCacheImport(args []string) error {
+	flagSet := newFlagSet("cache import", cacheCommandsMap["import"].short)
+	input := flagSet.String("i", "-", "archive file, optionally gzip compressed, - for stdin")
+
+	err := flagSet.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if *input != "-" {
+		f, err := os.Open(*input)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = f.Close() }()
+		r = f
+	}
+
+	br := bufio.NewReader(r)
+	codec := compress.None
+	if magic, err := br.Peek(len(gzipMagic)); err == nil && bytes.Equal(magic, gzipMagic) {
+		codec = compress.Gzip
+	}
+	cr, err := compress.NewReader(br, codec)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = cr.Close() }()
+
+	result, err := cache.Import(cr)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("imported %d entries, %d blobs, skipped %d entries\n", result.Entries, result.Blobs, result.Skipped)
+	return nil
+}
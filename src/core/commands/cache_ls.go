@@ -0,0 +1,149 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/oNaiPs/go-generate-fast/src/core/cache"
+	"github.com/oNaiPs/go-generate-fast/src/utils/str"
+)
+
+func init() {
+	registerCacheCommand(command{
+		name:  "ls",
+		short: "Lists the cache entries, with their size, usage and origin.",
+		run:   runCacheLs,
+	})
+}
+
+type lsEntry struct {
+	Key       string     `json:"key"`
+	Size      int64      `json:"size"`
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+	LastUsed  time.Time  `json:"lastUsed"`
+	Plugin    string     `json:"plugin,omitempty"`
+	Command   string     `json:"command,omitempty"`
+	File      string     `json:"file,omitempty"`
+	Outputs   []string   `json:"outputs"`
+	Error     string     `json:"error,omitempty"`
+}
+
+func newLsEntry(details cache.EntryDetails) lsEntry {
+	entry := lsEntry{
+		Key:      details.Key,
+		Size:     details.Size,
+		LastUsed: details.LastUsed,
+		Plugin:   details.Config.Plugin,
+		Command:  details.Config.Command,
+		File:     details.Config.File,
+		Outputs:  []string{},
+	}
+	if !details.Config.CreatedAt.IsZero() {
+		entry.CreatedAt = &details.Config.CreatedAt
+	}
+	for _, file := range details.Config.OutputFiles {
+		entry.Outputs = append(entry.Outputs, file.Path)
+	}
+	if details.Error != nil {
+		entry.Error = details.Error.Error()
+	}
+	return entry
+}
+
+func runCacheLs(args []string) error {
+	flagSet := newFlagSet("cache ls", cacheCommandsMap["ls"].short)
+	jsonOutput := flagSet.Bool("json", false, "print entries as JSON")
+	sortBy := flagSet.String("sort", "used", "sort entries by: used, created, size or key")
+
+	err := flagSet.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	details, err := cache.Inspect()
+	if err != nil {
+		return err
+	}
+
+	entries := []lsEntry{}
+	for _, d := range details {
+		entries = append(entries, newLsEntry(d))
+	}
+
+	err = sortLsEntries(entries, *sortBy)
+	if err != nil {
+		return err
+	}
+
+	if *jsonOutput {
+		return printJSON(entries)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "KEY\tSIZE\tCREATED\tLAST USED\tPLUGIN\tCOMMAND\tOUTPUTS")
+	for _, entry := range entries {
+		created := "-"
+		if entry.CreatedAt != nil {
+			created = formatTime(*entry.CreatedAt)
+		}
+		command := entry.Command
+		if entry.Error != "" {
+			command = "error: " + entry.Error
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			entry.Key,
+			str.FormatSize(entry.Size),
+			created,
+			formatTime(entry.LastUsed),
+			orDash(entry.Plugin),
+			orDash(command),
+			orDash(strings.Join(entry.Outputs, ",")))
+	}
+	return w.Flush()
+}
+
+func sortLsEntries(entries []lsEntry, sortBy string) error {
+	var less func(a, b lsEntry) bool
+	switch sortBy {
+	case "used":
+		less = func(a, b lsEntry) bool { return a.LastUsed.Before(b.LastUsed) }
+	case "created":
+		less = func(a, b lsEntry) bool {
+			return a.CreatedAt != nil && (b.CreatedAt == nil || a.CreatedAt.Before(*b.CreatedAt))
+		}
+	case "size":
+		less = func(a, b lsEntry) bool { return a.Size > b.Size }
+	case "key":
+		less = func(a, b lsEntry) bool { return a.Key < b.Key }
+	default:
+		return fmt.Errorf("unknown sort order %q", sortBy)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return less(entries[i], entries[j]) })
+	return nil
+}
+
+func printJSON(v any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,111 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/oNaiPs/go-generate-fast/src/core/cache"
+	"github.com/oNaiPs/go-generate-fast/src/core/config"
+	"github.com/oNaiPs/go-generate-fast/src/utils/str"
+)
+
+func init() {
+	registerCacheCommand(command{
+		name:  "stats",
+		short: "Prints a summary of the cache contents.",
+		run:   runCacheStats,
+	})
+}
+
+type statsPlugin struct {
+	Name    string `json:"name"`
+	Entries int    `json:"entries"`
+	Size    int64  `json:"size"`
+}
+
+type statsOutput struct {
+	Dir           string        `json:"dir"`
+	Entries       int           `json:"entries"`
+	TotalSize     int64         `json:"totalSize"`
+	SharedSize    int64         `json:"sharedSize"`
+	NeverRestored int           `json:"neverRestored"`
+	Invalid       int           `json:"invalid"`
+	OldestUsed    *time.Time    `json:"oldestUsed,omitempty"`
+	NewestUsed    *time.Time    `json:"newestUsed,omitempty"`
+	Plugins       []statsPlugin `json:"plugins"`
+}
+
+func runCacheStats(args []string) error {
+	flagSet := newFlagSet("cache stats", cacheCommandsMap["stats"].short)
+	jsonOutput := flagSet.Bool("json", false, "print stats as JSON")
+
+	err := flagSet.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	details, err := cache.Inspect()
+	if err != nil {
+		return err
+	}
+
+	stats := cache.ComputeStats(details)
+	output := statsOutput{
+		Dir:           config.Get().CacheDir,
+		Entries:       stats.Entries,
+		TotalSize:     stats.TotalSize,
+		SharedSize:    stats.SharedSize,
+		NeverRestored: stats.NeverRestored,
+		Invalid:       stats.Invalid,
+		Plugins:       []statsPlugin{},
+	}
+	if stats.Entries > 0 {
+		output.OldestUsed = &stats.OldestUsed
+		output.NewestUsed = &stats.NewestUsed
+	}
+	for name, pluginStats := range stats.Plugins {
+		output.Plugins = append(output.Plugins, statsPlugin{Name: name, Entries: pluginStats.Entries, Size: pluginStats.Size})
+	}
+	sort.Slice(output.Plugins, func(i, j int) bool {
+		return output.Plugins[i].Size > output.Plugins[j].Size
+	})
+
+	if *jsonOutput {
+		return printJSON(output)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(w, "Cache dir:\t%s\n", output.Dir)
+	_, _ = fmt.Fprintf(w, "Entries:\t%d\n", output.Entries)
+	_, _ = fmt.Fprintf(w, "Total size:\t%s\n", str.FormatSize(output.TotalSize))
+	if output.SharedSize > 0 {
+		_, _ = fmt.Fprintf(w, "Saved by sharing:\t%s\n", str.FormatSize(output.SharedSize))
+	}
+	_, _ = fmt.Fprintf(w, "Never restored:\t%d\n", output.NeverRestored)
+	if output.Invalid > 0 {
+		_, _ = fmt.Fprintf(w, "Invalid:\t%d\n", output.Invalid)
+	}
+	if output.OldestUsed != nil {
+		_, _ = fmt.Fprintf(w, "Oldest use:\t%s\n", formatTime(*output.OldestUsed))
+		_, _ = fmt.Fprintf(w, "Newest use:\t%s\n", formatTime(*output.NewestUsed))
+	}
+	err = w.Flush()
+	if err != nil || len(output.Plugins) == 0 {
+		return err
+	}
+
+	fmt.Println()
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "PLUGIN\tENTRIES\tSIZE")
+	for _, plugin := range output.Plugins {
+		name := plugin.Name
+		if name == "" {
+			name = "(custom)"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%d\t%s\n", name, plugin.Entries, str.FormatSize(plugin.Size))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/oNaiPs/go-generate-fast/src/core/cache"
+)
+
+func init() {
+	registerCacheCommand(command{
+		name:  "verify",
+		short: "Re-hashes the cached files of every entry and reports the corrupt entries.",
+		run:   runCacheVerify,
+	})
+}
+
+func runCacheVerify(args []string) error {
+	flagSet := newFlagSet("cache verify", cacheCommandsMap["verify"].short)
+	remove := flagSet.Bool("delete", false, "delete the corrupt entries")
+
+	err := flagSet.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	result, err := cache.CheckEntries(*remove)
+	if err != nil {
+		return err
+	}
+
+	removed := 0
+	for _, entry := range result.Corrupt {
+		status := "corrupt"
+		if entry.Removed {
+			status = "deleted"
+			removed++
+		}
+		fmt.Printf("%s\t%s\t%s\n", entry.Key, status, entry.Error)
+	}
+	fmt.Printf("checked %d entries, %d corrupt, %d deleted\n", result.Checked, len(result.Corrupt), removed)
+
+	if len(result.Corrupt) > removed && *remove {
+		return fmt.Errorf("cannot delete %d corrupt entries, in use", len(result.Corrupt)-removed)
+	} else if len(result.Corrupt) > removed {
+		return fmt.Errorf("found %d corrupt entries, use -delete to remove them", len(result.Corrupt))
+	}
+	return nil
+}
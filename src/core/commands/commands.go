@@ -0,0 +1,59 @@
+// Package commands implements the go-generate-fast subcommands.
+// A subcommand runs instead of the generation when the first argument
+// matches its name, e.g. "go-generate-fast serve".
+package commands
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/oNaiPs/go-generate-fast/src/core/generate/base"
+	"go.uber.org/zap"
+)
+
+type command struct {
+	name  string
+	short string
+	run   func(args []string) error
+}
+
+var commandsMap = make(map[string]command)
+
+func register(cmd command) {
+	commandsMap[cmd.name] = cmd
+}
+
+// Run runs the subcommand named by the first argument.
+// Returns false when args do not start with a known subcommand.
+func Run(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+
+	cmd, ok := commandsMap[args[0]]
+	if !ok {
+		return false
+	}
+
+	err := cmd.run(args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return true
+	}
+	if err != nil {
+		zap.S().Errorf("%s: %s", cmd.name, err)
+		base.SetExitStatus(1)
+	}
+
+	return true
+}
+
+// newFlagSet creates the flag set of a subcommand, listing its description on usage.
+func newFlagSet(name string, short string) *flag.FlagSet {
+	flagSet := flag.NewFlagSet(name, flag.ContinueOnError)
+	flagSet.Usage = func() {
+		_, _ = fmt.Fprintf(flagSet.Output(), "usage: go-generate-fast %s [flags]\n\n%s\n\n", name, short)
+		flagSet.PrintDefaults()
+	}
+	return flagSet
+}
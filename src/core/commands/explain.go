@@ -0,0 +1,26 @@
+package commands
+
+import (
+	"os"
+
+	"github.com/oNaiPs/go-generate-fast/src/core/generate/generate"
+)
+
+func init() {
+	register(command{
+		name:  "explain",
+		short: "Prints whether the directives of the given files or packages hit the cache, and why they miss it.",
+		run:   runExplain,
+	})
+}
+
+func runExplain(args []string) error {
+	flagSet := newFlagSet("explain", commandsMap["explain"].short)
+
+	err := flagSet.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	return generate.Explain(flagSet.Args(), os.Stdout)
+}
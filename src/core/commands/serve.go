@@ -0,0 +1,40 @@
+package commands
+
+import (
+	"net/http"
+
+	"github.com/oNaiPs/go-generate-fast/src/core/config"
+	"github.com/oNaiPs/go-generate-fast/src/core/server"
+	"go.uber.org/zap"
+)
+
+func init() {
+	register(command{
+		name:  "serve",
+		short: "Serves a cache directory over HTTP, to be used as a remote cache.",
+		run:   runServe,
+	})
+}
+
+func runServe(args []string) error {
+	flagSet := newFlagSet("serve", commandsMap["serve"].short)
+	addr := flagSet.String("addr", ":8080", "address to listen on")
+	dir := flagSet.String("dir", config.Get().CacheDir, "cache directory to serve")
+	token := flagSet.String("token", config.Get().RemoteToken, "bearer token required from clients (default $GO_GENERATE_FAST_REMOTE_TOKEN)")
+	readOnly := flagSet.Bool("read-only", false, "reject uploads")
+
+	err := flagSet.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	config.CreateDirIfNotExists(*dir, config.Get().DirPerm())
+
+	s := server.New(*dir)
+	s.Token = *token
+	s.ReadOnly = *readOnly
+
+	zap.S().Infof("Serving cache dir %s on %s (read-only: %t)", *dir, *addr, *readOnly)
+
+	return http.ListenAndServe(*addr, s)
+}
@@ -3,7 +3,14 @@ package config
 import (
 	"os"
 	"path"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
 
+	"github.com/oNaiPs/go-generate-fast/src/utils/compress"
+	"github.com/oNaiPs/go-generate-fast/src/utils/fs"
+	"github.com/oNaiPs/go-generate-fast/src/utils/str"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 )
@@ -16,8 +23,52 @@ type Config struct {
 	ReCache       bool
 	ForceUseCache bool
 	Debug         bool
+	// cache dir is shared by several users, entries are group writable
+	Shared bool
+	// codec of the saved blobs, empty to store them uncompressed
+	Compression string
+	// how input files are hashed, HashModeContent or HashModeGit
+	HashMode string
+	// how executables are identified, ExecFingerprintStat,
+	// ExecFingerprintContent or ExecFingerprintBuildInfo
+	ExecFingerprint string
+	// directory the paths in cache keys are relative to, empty for the module
+	// root of each directive
+	KeyRoot string
+	// added to all cache keys, changing it invalidates all entries
+	CacheSalt string
+	// names of the environment variables added to the cache keys, besides
+	// the ones referenced by the directives
+	EnvVars []string
+	// how long the versions resolved for @latest are reused, zero to resolve
+	// them on every run
+	LatestTTL     time.Duration
+	RemoteURL     string
+	RemoteTimeout time.Duration
+	RemoteToken   string
+	// cache garbage collection limits, zero when not set
+	MaxSize    int64
+	MaxAge     time.Duration
+	GCInterval time.Duration
 }
 
+const (
+	// input files are hashed by reading their content
+	HashModeContent = "content"
+	// clean tracked input files are identified by their git blob IDs
+	HashModeGit = "git"
+)
+
+const (
+	// executables are identified by their path, size and modification time
+	ExecFingerprintStat = "stat"
+	// executables are identified by the hash of their content
+	ExecFingerprintContent = "content"
+	// go executables are identified by their main module path and version,
+	// other executables by the hash of their content
+	ExecFingerprintBuildInfo = "buildinfo"
+)
+
 var instance *Config
 
 func Get() *Config {
@@ -52,22 +103,110 @@ func Init() {
 
 	viper.SetDefault("dir", userConfigDir)
 	instance.ConfigDir = viper.GetString("dir")
-	CreateDirIfNotExists(instance.ConfigDir)
+	CreateDirIfNotExists(instance.ConfigDir, 0700)
+
+	instance.Shared = viper.GetBool("shared")
 
 	viper.SetDefault("cache_dir", path.Join(instance.ConfigDir, "cache"))
 	instance.CacheDir = viper.GetString("cache_dir")
-	CreateDirIfNotExists(instance.CacheDir)
+	CreateDirIfNotExists(instance.CacheDir, instance.DirPerm())
 
 	instance.Disable = viper.GetBool("disable")
 	instance.ReadOnly = viper.GetBool("read_only")
 	instance.ReCache = viper.GetBool("recache")
 	instance.ForceUseCache = viper.GetBool("force_use_cache")
 	instance.Debug = viper.GetBool("debug")
+
+	instance.Compression = viper.GetString("compression")
+	if instance.Compression == "none" {
+		instance.Compression = compress.None
+	}
+	if err := compress.Validate(instance.Compression); err != nil {
+		zap.S().Errorf("Cannot use compression: %s", err)
+		instance.Compression = compress.None
+	}
+
+	viper.SetDefault("hash_mode", HashModeContent)
+	instance.HashMode = viper.GetString("hash_mode")
+	if instance.HashMode != HashModeContent && instance.HashMode != HashModeGit {
+		zap.S().Errorf("Cannot use hash mode %q, must be %s or %s", instance.HashMode, HashModeContent, HashModeGit)
+		instance.HashMode = HashModeContent
+	}
+
+	instance.CacheSalt = viper.GetString("cache_salt")
+
+	if keyRoot := viper.GetString("key_root"); keyRoot != "" {
+		instance.KeyRoot, err = filepath.Abs(keyRoot)
+		if err != nil {
+			zap.S().Errorf("Cannot use key_root: %s", err)
+		}
+	}
+
+	viper.SetDefault("exec_fingerprint", ExecFingerprintStat)
+	instance.ExecFingerprint = viper.GetString("exec_fingerprint")
+	if instance.ExecFingerprint != ExecFingerprintStat &&
+		instance.ExecFingerprint != ExecFingerprintContent &&
+		instance.ExecFingerprint != ExecFingerprintBuildInfo {
+		zap.S().Errorf("Cannot use exec fingerprint %q, must be %s, %s or %s", instance.ExecFingerprint,
+			ExecFingerprintStat, ExecFingerprintContent, ExecFingerprintBuildInfo)
+		instance.ExecFingerprint = ExecFingerprintStat
+	}
+
+	for _, names := range viper.GetStringSlice("env_vars") {
+		instance.EnvVars = append(instance.EnvVars, strings.FieldsFunc(names, func(r rune) bool {
+			return r == ',' || unicode.IsSpace(r)
+		})...)
+	}
+
+	instance.RemoteURL = viper.GetString("remote_url")
+	viper.SetDefault("remote_timeout", 10*time.Second)
+	instance.RemoteTimeout = viper.GetDuration("remote_timeout")
+	instance.RemoteToken = viper.GetString("remote_token")
+
+	if maxSize := viper.GetString("max_size"); maxSize != "" {
+		instance.MaxSize, err = str.ParseSize(maxSize)
+		if err != nil {
+			zap.S().Errorf("Cannot parse max_size: %s", err)
+		}
+	}
+	if maxAge := viper.GetString("max_age"); maxAge != "" {
+		instance.MaxAge, err = str.ParseDuration(maxAge)
+		if err != nil {
+			zap.S().Errorf("Cannot parse max_age: %s", err)
+		}
+	}
+	viper.SetDefault("latest_ttl", "1h")
+	instance.LatestTTL, err = str.ParseDuration(viper.GetString("latest_ttl"))
+	if err != nil {
+		zap.S().Errorf("Cannot parse latest_ttl: %s", err)
+	}
+	viper.SetDefault("gc_interval", "1h")
+	instance.GCInterval, err = str.ParseDuration(viper.GetString("gc_interval"))
+	if err != nil {
+		zap.S().Errorf("Cannot parse gc_interval: %s", err)
+	}
+}
+
+// DirPerm returns the permissions of the directories created on the cache dir.
+func (c *Config) DirPerm() os.FileMode {
+	if c.Shared {
+		// new files inherit the group of the cache dir
+		return 0770 | os.ModeSetgid
+	}
+	return 0700
+}
+
+// FilePerm returns the permissions of the files created on the cache dir.
+func (c *Config) FilePerm() os.FileMode {
+	if c.Shared {
+		return 0660
+	}
+	return 0600
 }
 
-func CreateDirIfNotExists(path string) {
+func CreateDirIfNotExists(path string, perm os.FileMode) {
 	if _, err := os.Stat(path); os.IsNotExist(err) {
-		err = os.MkdirAll(path, 0700)
+		err = fs.MkdirAll(path, perm)
 		if err != nil {
 			zap.S().Fatal("Error creating config directory: ", err)
 			return
@@ -5,6 +5,7 @@ import (
 	"path"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
@@ -22,6 +23,8 @@ func TestConfigGet(t *testing.T) {
 	t.Setenv("GO_GENERATE_FAST_DISABLE", strconv.FormatBool(expectedDisable))
 	t.Setenv("GO_GENERATE_FAST_READ_ONLY", strconv.FormatBool(expectedReadOnly))
 	t.Setenv("GO_GENERATE_FAST_RECACHE", strconv.FormatBool(expectedReCache))
+	t.Setenv("GO_GENERATE_FAST_CACHE_SALT", "2")
+	t.Setenv("GO_GENERATE_FAST_ENV_VARS", "CGO_ENABLED,GOEXPERIMENT PROTOC_FLAGS")
 
 	Init()
 
@@ -35,6 +38,11 @@ func TestConfigGet(t *testing.T) {
 	assert.Equal(t, expectedDisable, config.Disable)
 	assert.Equal(t, expectedReadOnly, config.ReadOnly)
 	assert.Equal(t, expectedReCache, config.ReCache)
+	assert.Equal(t, HashModeContent, config.HashMode)
+	assert.Equal(t, ExecFingerprintStat, config.ExecFingerprint)
+	assert.Equal(t, time.Hour, config.LatestTTL)
+	assert.Equal(t, "2", config.CacheSalt)
+	assert.Equal(t, []string{"CGO_ENABLED", "GOEXPERIMENT", "PROTOC_FLAGS"}, config.EnvVars)
 }
 
 func TestConfigCreateDirIfNotExists(t *testing.T) {
@@ -42,7 +50,7 @@ func TestConfigCreateDirIfNotExists(t *testing.T) {
 	tmpDir := path.Join(t.TempDir(), "create-dir-test")
 
 	// Call the function with the test directory
-	CreateDirIfNotExists(tmpDir)
+	CreateDirIfNotExists(tmpDir, 0700)
 
 	// Assert that the directory exists
 	_, err := os.Stat(tmpDir)
@@ -53,3 +61,20 @@ func TestConfigCreateDirIfNotExists(t *testing.T) {
 	assert.True(t, info.IsDir(), "path is not a directory")
 	assert.Equal(t, os.FileMode(0700), info.Mode().Perm(), "incorrect directory permissions")
 }
+
+func TestConfigPerm(t *testing.T) {
+	conf := Config{}
+	assert.Equal(t, os.FileMode(0700), conf.DirPerm())
+	assert.Equal(t, os.FileMode(0600), conf.FilePerm())
+
+	conf.Shared = true
+	assert.Equal(t, os.FileMode(0770)|os.ModeSetgid, conf.DirPerm())
+	assert.Equal(t, os.FileMode(0660), conf.FilePerm())
+
+	tmpDir := path.Join(t.TempDir(), "shared", "cache")
+	CreateDirIfNotExists(tmpDir, conf.DirPerm())
+
+	info, err := os.Stat(tmpDir)
+	assert.NoError(t, err)
+	assert.Equal(t, conf.DirPerm(), info.Mode()&(os.ModePerm|os.ModeSetgid), "permissions shall not depend on the umask")
+}
@@ -10,6 +10,8 @@ var (
 	BuildN bool // -n flag
 	BuildV bool // -v flag
 	BuildX bool // -x flag
+
+	Explain bool // -explain flag
 )
 
 func init() {
@@ -18,6 +20,7 @@ func init() {
 	flags.BoolVar(&BuildN, "n", false, "")
 	flags.BoolVar(&BuildV, "v", false, "")
 	flags.BoolVar(&BuildX, "x", false, "")
+	flags.BoolVar(&Explain, "explain", false, "")
 
 	err := flags.Parse(os.Args[1:])
 	if err != nil {
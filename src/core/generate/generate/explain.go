@@ -0,0 +1,93 @@
+package generate
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/oNaiPs/go-generate-fast/src/core/cache"
+	"github.com/oNaiPs/go-generate-fast/src/core/config"
+	"github.com/oNaiPs/go-generate-fast/src/core/loader"
+)
+
+// Explain verifies the directives of the packages or files in args, without
+// running them, and prints whether they hit the cache or why they miss it.
+func Explain(args []string, w io.Writer) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("cannot get working directory: %w", err)
+	}
+
+	l := loader.New()
+	for _, pkg := range l.GoFiles("", args) {
+		if pkg.Error != nil {
+			return errors.New(*pkg.Error)
+		}
+
+		directives, ok := readDirectives(pkg.Package, l)
+		if !ok {
+			return fmt.Errorf("cannot read the directives of %s", pkg.Package)
+		}
+
+		for i := range directives {
+			d := &directives[i]
+			verifyDirective(d)
+
+			_, _ = fmt.Fprintf(w, "%s:%d: %s\n", relativePath(cwd, pkg.Package), d.lineNum, d.command)
+			for _, reason := range explainDirective(d) {
+				_, _ = fmt.Fprintf(w, "\t%s\n", reason)
+			}
+		}
+	}
+	return nil
+}
+
+// explainDirective returns why a verified directive hits or misses the cache.
+// On a miss, the components of its key that changed since the closest entry
+// saved for it are listed.
+func explainDirective(d *directiveInfo) []string {
+	switch {
+	case config.Get().Disable:
+		return []string{"caching is disabled"}
+	case !d.canCache:
+		return []string{fmt.Sprintf("cannot compute the cache key: %s", d.verifyErr)}
+	case d.cacheResult.CacheHitDir == "":
+		return []string{"not cacheable, its input and output files are unknown"}
+	case config.Get().ReCache:
+		return []string{"recaching, existing entries are not used"}
+	case d.cacheResult.CacheHit:
+		return []string{"cache hit, entry " + d.cacheResult.CacheKey}
+	}
+
+	explanation, err := cache.Explain(d.cacheResult)
+	if err != nil {
+		return []string{fmt.Sprintf("cannot explain the cache miss: %s", err)}
+	}
+	if explanation.Entry == nil {
+		return []string{"cache miss, no entry was saved for this directive"}
+	}
+
+	reasons := []string{fmt.Sprintf("cache miss, closest entry %s saved at %s, changed:",
+		explanation.Entry.Key, explanation.Entry.Config.CreatedAt.Format(time.RFC3339))}
+	for _, change := range explanation.Changes {
+		reasons = append(reasons, "  "+change.String())
+	}
+	return reasons
+}
+
+// withoutExplainFlag returns args without the -explain flag, which go list
+// does not know.
+func withoutExplainFlag(args []string) []string {
+	var filtered []string
+	for _, arg := range args {
+		name := strings.TrimLeft(arg, "-")
+		if strings.HasPrefix(arg, "-") && (name == "explain" || strings.HasPrefix(name, "explain=")) {
+			continue
+		}
+		filtered = append(filtered, arg)
+	}
+	return filtered
+}
@@ -6,6 +6,7 @@ package generate
 import (
 	"bufio"
 	"bytes"
+	"cmp"
 	"fmt"
 	"go/parser"
 	"go/token"
@@ -24,10 +25,13 @@ import (
 	"github.com/oNaiPs/go-generate-fast/src/core/config"
 	"github.com/oNaiPs/go-generate-fast/src/core/generate/base"
 	"github.com/oNaiPs/go-generate-fast/src/core/generate/cfg"
-	"github.com/oNaiPs/go-generate-fast/src/core/golist"
+	"github.com/oNaiPs/go-generate-fast/src/core/gitindex"
+	"github.com/oNaiPs/go-generate-fast/src/core/loader"
+	"github.com/oNaiPs/go-generate-fast/src/core/statcache"
 	"github.com/oNaiPs/go-generate-fast/src/plugins"
 	"github.com/oNaiPs/go-generate-fast/src/utils/fs"
 	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
 )
 
 var (
@@ -54,15 +58,53 @@ func RunGenerate(args []string) {
 		}
 	}
 
-	for _, pkg := range golist.ModulesAndErrors(args) {
+	if !config.Get().Disable && !config.Get().ReadOnly {
+		cache.MarkRunStart()
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		zap.S().Fatalf("cannot get working directory: %s", err)
+	}
+
+	// package metadata is shared by the directives of the run
+	l := loader.New()
+
+	var files []fileDirectives
+	for _, pkg := range l.GoFiles("", withoutExplainFlag(args)) {
 		if pkg.Error != nil {
 			fmt.Println(*pkg.Error)
 			continue
 		}
 
-		if !generate(pkg.Package) {
+		directives, ok := readDirectives(pkg.Package, l)
+		if !ok {
 			break
 		}
+		if len(directives) > 0 {
+			files = append(files, fileDirectives{absFile: pkg.Package, directives: directives})
+		}
+	}
+
+	verifyDirectives(files)
+
+	// set once a file was written by the run, the directives verified earlier
+	// may depend on it
+	changed := false
+	for i := range files {
+		if !generate(&files[i], cwd, l, &changed) {
+			break
+		}
+	}
+
+	if !config.Get().Disable {
+		err := statcache.Save()
+		if err != nil {
+			zap.S().Debugf("cannot save stat cache: %s", err)
+		}
+	}
+	if !config.Get().Disable && !config.Get().ReadOnly {
+		cache.AutoGC()
 	}
 }
 
@@ -71,11 +113,19 @@ type directiveInfo struct {
 	command     string
 	opts        plugins.GenerateOpts
 	cacheResult cache.VerifyResult
+	verifyErr   error
 	needsRun    bool
 	canCache    bool
 }
 
-func generate(absFile string) bool {
+type fileDirectives struct {
+	absFile    string
+	directives []directiveInfo
+}
+
+// readDirectives returns the directives of a file. Returns false when the
+// run must stop.
+func readDirectives(absFile string, l *loader.Loader) ([]directiveInfo, bool) {
 	src, err := os.ReadFile(absFile)
 	if err != nil {
 		log.Fatalf("generate: %s", err)
@@ -83,76 +133,107 @@ func generate(absFile string) bool {
 
 	filePkg, err := parser.ParseFile(token.NewFileSet(), "", src, parser.PackageClauseOnly)
 	if err != nil {
-		return true
+		return nil, true
 	}
 
 	if cfg.BuildV {
 		zap.S().Debug(absFile)
 	}
 
-	directives, err := scanDirectives(absFile, src, filePkg.Name.String())
+	directives, err := scanDirectives(absFile, src, filePkg.Name.String(), l)
 	if err != nil {
 		zap.S().Errorf("error scanning %s: %s", absFile, err)
 		base.SetExitStatus(1)
-		return false
+		return nil, false
 	}
 
-	if len(directives) == 0 {
-		return true
+	return directives, true
+}
+
+// verifyDirectives verifies the directives of all files concurrently. It
+// only reads files, and does not depend on the working directory.
+func verifyDirectives(files []fileDirectives) {
+	var g errgroup.Group
+	g.SetLimit(runtime.GOMAXPROCS(0))
+
+	for i := range files {
+		for j := range files[i].directives {
+			g.Go(func() error {
+				verifyDirective(&files[i].directives[j])
+				return nil
+			})
+		}
 	}
 
-	cwd, err := os.Getwd()
-	if err != nil {
-		zap.S().Fatalf("cannot get working directory: %s", err)
+	_ = g.Wait()
+}
+
+// generate restores or runs the directives of a file, and saves their outputs.
+// Directives are verified again when files were written since they were
+// first verified, and the loaded packages are reset once files are written.
+// Returns false when the run must stop.
+func generate(file *fileDirectives, cwd string, l *loader.Loader, changed *bool) bool {
+	if *changed {
+		directives, ok := readDirectives(file.absFile, l)
+		if !ok {
+			return false
+		}
+		file.directives = directives
 	}
 
 	anyNeedRun := false
-	for i := range directives {
-		dir := directives[i].opts.Dir()
-
-		if err := os.Chdir(dir); err != nil {
-			zap.S().Fatalf("cannot chdir to command directory: %s", err)
+	for i := range file.directives {
+		d := &file.directives[i]
+		if *changed {
+			verifyDirective(d)
 		}
 
-		checkDirectiveCache(&directives[i])
-		if directives[i].needsRun {
+		if restoreDirective(d) > 0 {
+			*changed = true
+			l.Reset()
+			gitindex.Reset()
+		}
+		if d.needsRun {
 			anyNeedRun = true
 		}
+	}
 
-		if err := os.Chdir(cwd); err != nil {
-			zap.S().Fatalf("cannot restore working directory: %s", err)
+	if cfg.Explain {
+		for i := range file.directives {
+			d := &file.directives[i]
+			if !d.needsRun {
+				continue
+			}
+			for _, reason := range explainDirective(d) {
+				zap.S().Infof("%s:%d: %s", relativePath(cwd, file.absFile), d.lineNum, reason)
+			}
 		}
 	}
 
 	if anyNeedRun {
 		if config.Get().ForceUseCache {
-			zap.S().Errorf("force_use_cache mode but cache miss for %s", absFile)
+			zap.S().Errorf("force_use_cache mode but cache miss for %s", file.absFile)
 			base.SetExitStatus(1)
 		} else {
-			if !executeGoGenerate(absFile) {
+			*changed = true
+			ok := executeGoGenerate(file.absFile)
+			l.Reset()
+			gitindex.Reset()
+			if !ok {
 				base.SetExitStatus(1)
 				return false
 			}
 		}
 	}
 
-	for i := range directives {
-		dir := directives[i].opts.Dir()
-		if err := os.Chdir(dir); err != nil {
-			zap.S().Fatalf("cannot chdir: %s", err)
-		}
-
-		saveAndReportDirective(&directives[i], absFile, cwd, anyNeedRun)
-
-		if err := os.Chdir(cwd); err != nil {
-			zap.S().Fatalf("cannot restore working directory: %s", err)
-		}
+	for i := range file.directives {
+		saveAndReportDirective(&file.directives[i], file.absFile, cwd, anyNeedRun)
 	}
 
 	return true
 }
 
-func scanDirectives(absFile string, src []byte, pkg string) ([]directiveInfo, error) {
+func scanDirectives(absFile string, src []byte, pkg string, l *loader.Loader) ([]directiveInfo, error) {
 	var directives []directiveInfo
 	input := bufio.NewReader(bytes.NewReader(src))
 
@@ -257,13 +338,17 @@ func scanDirectives(absFile string, src []byte, pkg string) ([]directiveInfo, er
 
 		// Expand environment variables in words for plugin matching
 		envMap["GOLINE"] = strconv.Itoa(lineNum)
-		expandedWords := expandWords(words, envMap)
+		expandedWords, referencedEnv := expandWords(words, envMap)
 
 		opts := plugins.GenerateOpts{
 			Path:                absFile,
+			Line:                lineNum,
+			Directive:           command,
 			Words:               expandedWords,
+			Env:                 directiveEnv(expandedWords, referencedEnv),
 			ExtraInputPatterns:  append([]string{}, extraInputPatterns...),
 			ExtraOutputPatterns: append([]string{}, extraOutputPatterns...),
+			Loader:              l,
 		}
 
 		// Handle env variable prefix (e.g., "env VAR=value command args...")
@@ -289,6 +374,9 @@ func scanDirectives(absFile string, src []byte, pkg string) ([]directiveInfo, er
 			opts.SanitizedArgs = tempOpts.SanitizedArgs
 			opts.GoPackage = tempOpts.GoPackage
 			opts.GoPackageVersion = tempOpts.GoPackageVersion
+			opts.GoTool = tempOpts.GoTool
+			opts.GoBuildFlags = tempOpts.GoBuildFlags
+			opts.GoFiles = tempOpts.GoFiles
 		}
 
 		directives = append(directives, directiveInfo{
@@ -346,10 +434,40 @@ func skipEnvPrefix(words []string) []string {
 	return words
 }
 
+// directiveEnv returns the environment variables a directive depends on: the
+// ones referenced by its words, the ones set by its env prefix, GOFLAGS and
+// the ones configured by the user.
+func directiveEnv(words []string, referenced map[string]string) map[string]string {
+	env := map[string]string{}
+	for name, value := range referenced {
+		env[name] = value
+	}
+
+	for _, name := range append([]string{"GOFLAGS"}, config.Get().EnvVars...) {
+		if value := os.Getenv(name); value != "" {
+			env[name] = value
+		}
+	}
+
+	if len(words) > 0 && words[0] == "env" {
+		for _, word := range words[1:] {
+			name, value, ok := strings.Cut(word, "=")
+			if !ok {
+				break
+			}
+			env[name] = value
+		}
+	}
+
+	return env
+}
+
+// buildEnvMap returns the variables go generate sets for a file. GOOS and
+// GOARCH honor the environment, like the build context of go generate.
 func buildEnvMap(absFile string, pkg string) map[string]string {
 	return map[string]string{
-		"GOARCH":    runtime.GOARCH,
-		"GOOS":      runtime.GOOS,
+		"GOARCH":    cmp.Or(os.Getenv("GOARCH"), runtime.GOARCH),
+		"GOOS":      cmp.Or(os.Getenv("GOOS"), runtime.GOOS),
 		"GOFILE":    filepath.Base(absFile),
 		"GOPACKAGE": pkg,
 		"DOLLAR":    "$",
@@ -357,50 +475,56 @@ func buildEnvMap(absFile string, pkg string) map[string]string {
 	}
 }
 
-func expandWords(words []string, envMap map[string]string) []string {
+// expandWords expands the variables of words, and returns the expanded words
+// and the values of the variables they reference, by name.
+func expandWords(words []string, envMap map[string]string) ([]string, map[string]string) {
 	expanded := make([]string, len(words))
+	referenced := map[string]string{}
 	for i, word := range words {
 		expanded[i] = os.Expand(word, func(key string) string {
-			if val, ok := envMap[key]; ok {
-				return val
+			val, ok := envMap[key]
+			if !ok {
+				val = os.Getenv(key)
 			}
-			return os.Getenv(key)
+			referenced[key] = val
+			return val
 		})
 	}
-	return expanded
+	return expanded, referenced
 }
 
-func checkDirectiveCache(d *directiveInfo) {
+// verifyDirective computes the cache entry of a directive.
+func verifyDirective(d *directiveInfo) {
 	if config.Get().Disable {
-		d.needsRun = true
 		return
 	}
 
 	cacheResult, err := cache.Verify(d.opts)
 	d.cacheResult = cacheResult
+	d.verifyErr = err
 	d.canCache = err == nil
 
 	if err != nil {
 		zap.S().Debugf("cannot verify cache: %s", err)
-		d.needsRun = true
-		return
 	}
+}
 
-	if config.Get().ReCache {
+// restoreDirective restores the outputs of a verified directive on a cache
+// hit, or marks it as needing to run. Returns the number of restored files.
+func restoreDirective(d *directiveInfo) int {
+	if config.Get().Disable || !d.canCache || config.Get().ReCache || !d.cacheResult.CacheHit {
 		d.needsRun = true
-		return
+		return 0
 	}
 
-	if cacheResult.CacheHit {
-		if err := cache.Restore(cacheResult); err != nil {
-			zap.S().Errorf("cannot restore cache: %s", err)
-			d.needsRun = true
-		} else {
-			d.needsRun = false
-		}
-	} else {
+	restored, err := cache.Restore(d.cacheResult)
+	if err != nil {
+		zap.S().Errorf("cannot restore cache: %s", err)
 		d.needsRun = true
+	} else {
+		d.needsRun = false
 	}
+	return restored
 }
 
 func executeGoGenerate(absFile string) bool {
@@ -444,11 +568,15 @@ func saveAndReportDirective(d *directiveInfo, absFile string, cwd string, fileWa
 
 	cachedInfo = append(cachedInfo, fmt.Sprintf("%dms", time.Since(start).Milliseconds()))
 
+	zap.S().Infof("%s: %s (%s)", relativePath(cwd, absFile), d.command, strings.Join(cachedInfo, ", "))
+}
+
+func relativePath(cwd string, absFile string) string {
 	relPath, _ := filepath.Rel(cwd, absFile)
 	if relPath == "" {
 		relPath = absFile
 	}
-	zap.S().Infof("%s: %s (%s)", relPath, d.command, strings.Join(cachedInfo, ", "))
+	return relPath
 }
 
 func parseGoToolCommand(opts *plugins.GenerateOpts) bool {
@@ -458,6 +586,7 @@ func parseGoToolCommand(opts *plugins.GenerateOpts) bool {
 	if opts.Words[0] != "go" || opts.Words[1] != "tool" {
 		return false
 	}
+	opts.GoTool = opts.Words[2]
 	opts.ExecutableName = filepath.Base(opts.Words[2])
 	opts.SanitizedArgs = opts.Words[3:]
 	return true
@@ -473,17 +602,36 @@ func parseGoRunCommand(opts *plugins.GenerateOpts) bool {
 
 	pkgIdx := 2
 	for pkgIdx < len(opts.Words) && strings.HasPrefix(opts.Words[pkgIdx], "-") {
+		flag := opts.Words[pkgIdx]
 		pkgIdx++
-		// Skip flag value if this flag takes an argument
-		if pkgIdx < len(opts.Words) && !strings.HasPrefix(opts.Words[pkgIdx], "-") {
+
+		name := strings.TrimLeft(flag, "-")
+		if !strings.Contains(name, "=") && goRunValueFlags[name] {
+			if pkgIdx >= len(opts.Words) {
+				return false
+			}
+			flag += "=" + opts.Words[pkgIdx]
 			pkgIdx++
 		}
+		opts.GoBuildFlags = append(opts.GoBuildFlags, flag)
 	}
 
 	if pkgIdx >= len(opts.Words) {
 		return false
 	}
 
+	// go run file.go... runs all the consecutive go files
+	if strings.HasSuffix(opts.Words[pkgIdx], ".go") {
+		filesEnd := pkgIdx
+		for filesEnd < len(opts.Words) && strings.HasSuffix(opts.Words[filesEnd], ".go") {
+			filesEnd++
+		}
+		opts.GoFiles = opts.Words[pkgIdx:filesEnd]
+		opts.GoPackage = opts.GoFiles[0]
+		opts.SanitizedArgs = opts.Words[filesEnd:]
+		return true
+	}
+
 	packageAndVersion := strings.Split(opts.Words[pkgIdx], "@")
 	opts.GoPackage = packageAndVersion[0]
 	if len(packageAndVersion) > 1 {
@@ -492,3 +640,27 @@ func parseGoRunCommand(opts *plugins.GenerateOpts) bool {
 	opts.SanitizedArgs = opts.Words[pkgIdx+1:]
 	return true
 }
+
+// goRunValueFlags are the go run flags followed by a value, as listed by
+// `go help build`. Other flags are boolean.
+var goRunValueFlags = map[string]bool{
+	"C":             true,
+	"p":             true,
+	"asmflags":      true,
+	"buildmode":     true,
+	"compiler":      true,
+	"covermode":     true,
+	"coverpkg":      true,
+	"exec":          true,
+	"gccgoflags":    true,
+	"gcflags":       true,
+	"installsuffix": true,
+	"ldflags":       true,
+	"mod":           true,
+	"modfile":       true,
+	"overlay":       true,
+	"pgo":           true,
+	"pkgdir":        true,
+	"tags":          true,
+	"toolexec":      true,
+}
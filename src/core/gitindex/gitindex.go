@@ -0,0 +1,232 @@
+// Package gitindex looks up the blob IDs git already computed for the clean
+// tracked files of a repository, so that their content does not need to be
+// read to be hashed. Other files are hashed the way git would.
+package gitindex
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oNaiPs/go-generate-fast/src/utils/fs"
+	"go.uber.org/zap"
+)
+
+// files changed this close to the snapshot are not trusted to be clean, as the
+// change may not have been seen by git, or be hidden by the timestamp granularity
+var racyWindow = 2 * time.Second
+
+// snapshot holds the blob IDs of the clean tracked files of a repository.
+type snapshot struct {
+	// hash of the objects of the repository, sha1 or sha256
+	objectFormat string
+	// blob IDs by absolute file path
+	blobs map[string]string
+	// time the files were found clean, later changes make them dirty again
+	takenAt time.Time
+}
+
+var (
+	mu sync.Mutex
+	// repository root of each directory, empty when not in a repository
+	roots = map[string]string{}
+	// snapshots by repository root, nil when it cannot be read
+	snapshots = map[string]*snapshot{}
+)
+
+// BlobIDs returns the git blob IDs of the files that are tracked and unmodified
+// in the repository containing dir, by file, and the object format of the
+// repository. Relative files are taken relative to dir. Files missing from the
+// result must be hashed by the caller with HashFile, in the same object
+// format, so that the same content always has the same ID. The object format
+// is empty when dir is not in a repository.
+func BlobIDs(dir string, files []string) (map[string]string, string) {
+	ids := map[string]string{}
+
+	s := getSnapshot(dir)
+	if s == nil {
+		return ids, ""
+	}
+
+	for _, file := range files {
+		absPath := file
+		if !filepath.IsAbs(file) {
+			absPath = filepath.Join(dir, file)
+		}
+
+		id, ok := s.blobs[filepath.Clean(absPath)]
+		if !ok {
+			continue
+		}
+
+		// modified by a generator of this run, after the snapshot
+		stat, err := fs.Stat(absPath)
+		if err != nil || max(stat.ModTime, stat.ChangeTime) >= s.takenAt.Add(-racyWindow).UnixNano() {
+			continue
+		}
+
+		ids[file] = id
+	}
+
+	return ids, s.objectFormat
+}
+
+// HashFile returns the blob ID of a file in an object format, sha1 or sha256,
+// like git hash-object.
+func HashFile(file string, objectFormat string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	h := sha1.New()
+	if objectFormat == "sha256" {
+		h = sha256.New()
+	}
+
+	_, _ = fmt.Fprintf(h, "blob %d\x00", stat.Size())
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", err
+	}
+	if n != stat.Size() {
+		return "", fmt.Errorf("file changed while hashing")
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// getSnapshot returns the snapshot of the repository containing dir, taking
+// it on first use. Returns nil when dir is not in a repository.
+func getSnapshot(dir string) *snapshot {
+	mu.Lock()
+	defer mu.Unlock()
+
+	root, ok := roots[dir]
+	if !ok {
+		var err error
+		root, err = findRoot(dir)
+		if err != nil {
+			zap.S().Debugf("Cannot find git repository of %s, hashing files instead: %s", dir, err)
+		}
+		roots[dir] = root
+	}
+	if root == "" {
+		return nil
+	}
+
+	if s, ok := snapshots[root]; ok {
+		return s
+	}
+
+	s, err := takeSnapshot(root)
+	if err != nil {
+		zap.S().Debugf("Cannot get git blob IDs of %s, hashing files instead: %s", root, err)
+	}
+	snapshots[root] = s
+	return s
+}
+
+// Reset forgets the snapshots of the repositories. It must be called once the
+// run changes files, which may no longer match the index.
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	snapshots = map[string]*snapshot{}
+}
+
+// findRoot returns the root of the repository containing dir, spelled like dir.
+func findRoot(dir string) (string, error) {
+	prefix, err := git(dir, "rev-parse", "--show-prefix")
+	if err != nil {
+		return "", err
+	}
+
+	root := dir
+	for range strings.Count(strings.TrimSpace(prefix), "/") {
+		root = filepath.Dir(root)
+	}
+	return root, nil
+}
+
+func takeSnapshot(root string) (*snapshot, error) {
+	takenAt := time.Now()
+
+	objectFormat, err := git(root, "rev-parse", "--show-object-format")
+	if err != nil {
+		return nil, err
+	}
+	objectFormat = strings.TrimSpace(objectFormat)
+	if objectFormat != "sha1" && objectFormat != "sha256" {
+		return nil, fmt.Errorf("unknown object format %q", objectFormat)
+	}
+
+	// -v tags the files git does not check for changes, which are not trusted
+	index, err := git(root, "ls-files", "-s", "-v", "-z")
+	if err != nil {
+		return nil, err
+	}
+
+	modified, err := git(root, "diff-files", "--name-only", "-z")
+	if err != nil {
+		return nil, err
+	}
+	dirty := map[string]bool{}
+	for _, name := range strings.Split(modified, "\x00") {
+		dirty[name] = true
+	}
+
+	s := &snapshot{objectFormat: objectFormat, blobs: map[string]string{}, takenAt: takenAt}
+	for _, line := range strings.Split(index, "\x00") {
+		// <tag> <mode> <object> <stage>\t<file>
+		info, name, ok := strings.Cut(line, "\t")
+		if !ok || dirty[name] {
+			continue
+		}
+
+		fields := strings.Fields(info)
+		if len(fields) != 4 {
+			continue
+		}
+		tag, mode, id, stage := fields[0], fields[1], fields[2], fields[3]
+
+		// only regular files, the blob of a symlink or submodule does not identify the content read through it
+		if tag != "H" || stage != "0" || (mode != "100644" && mode != "100755") {
+			continue
+		}
+
+		s.blobs[filepath.Join(root, filepath.FromSlash(name))] = id
+	}
+
+	zap.S().Debugf("Got %d clean tracked files from git index of %s", len(s.blobs), root)
+
+	return s, nil
+}
+
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
@@ -0,0 +1,120 @@
+package gitindex
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func reset(t *testing.T) {
+	t.Helper()
+
+	oldRacyWindow := racyWindow
+	// files written by the test are trusted
+	racyWindow = 0
+	t.Cleanup(func() {
+		racyWindow = oldRacyWindow
+		roots = map[string]string{}
+		snapshots = map[string]*snapshot{}
+	})
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@test"}, args...)...)
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+	return strings.TrimSpace(string(out))
+}
+
+func TestBlobIDs(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	reset(t)
+
+	root := t.TempDir()
+	runGit(t, root, "init", "-q")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "sub"), 0755))
+	for _, name := range []string{"sub/clean", "sub/modified", "sub/unchecked", "top"} {
+		require.NoError(t, os.WriteFile(filepath.Join(root, name), []byte(name), 0644))
+	}
+	require.NoError(t, os.Symlink("top", filepath.Join(root, "sub", "link")))
+	runGit(t, root, "add", ".")
+	runGit(t, root, "commit", "-q", "-m", "init")
+	runGit(t, root, "update-index", "--assume-unchanged", "sub/unchecked")
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, "sub", "modified"), []byte("changed"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "sub", "untracked"), []byte("untracked"), 0644))
+
+	dir := filepath.Join(root, "sub")
+	files := []string{"clean", "modified", "unchecked", "untracked", "link", "../top", filepath.Join(root, "top")}
+	ids, objectFormat := BlobIDs(dir, files)
+	assert.Equal(t, "sha1", objectFormat)
+
+	topID := runGit(t, root, "hash-object", "top")
+	assert.Equal(t, map[string]string{
+		"clean":                    runGit(t, dir, "hash-object", "clean"),
+		"../top":                   topID,
+		filepath.Join(root, "top"): topID,
+	}, ids)
+
+	// modified after the snapshot, within the timestamp granularity
+	racyWindow = time.Second
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "clean"), []byte("changed"), 0644))
+	ids, _ = BlobIDs(dir, files)
+	assert.NotContains(t, ids, "clean")
+
+	// committed by a generator of this run
+	racyWindow = 0
+	runGit(t, root, "commit", "-q", "-a", "-m", "generate")
+	ids, _ = BlobIDs(dir, files)
+	assert.NotContains(t, ids, "modified", "snapshot is kept until reset")
+	Reset()
+	ids, _ = BlobIDs(dir, files)
+	assert.Equal(t, runGit(t, dir, "hash-object", "modified"), ids["modified"])
+}
+
+func TestBlobIDsNoRepository(t *testing.T) {
+	reset(t)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file"), []byte("file"), 0644))
+
+	ids, objectFormat := BlobIDs(dir, []string{"file"})
+	assert.Empty(t, ids)
+	assert.Equal(t, "", objectFormat)
+	assert.Contains(t, roots, dir)
+	assert.Equal(t, "", roots[dir])
+}
+
+func TestHashFile(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+
+	for _, objectFormat := range []string{"sha1", "sha256"} {
+		t.Run(objectFormat, func(t *testing.T) {
+			reset(t)
+
+			root := t.TempDir()
+			runGit(t, root, "init", "-q", "--object-format="+objectFormat)
+			file := filepath.Join(root, "file")
+			require.NoError(t, os.WriteFile(file, []byte("content\n"), 0644))
+
+			_, format := BlobIDs(root, nil)
+			assert.Equal(t, objectFormat, format)
+
+			id, err := HashFile(file, format)
+			require.NoError(t, err)
+			assert.Equal(t, runGit(t, root, "hash-object", "file"), id)
+		})
+	}
+}
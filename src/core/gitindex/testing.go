@@ -0,0 +1,21 @@
+//go:build test
+
+package gitindex
+
+import "testing"
+
+// TrustRecentChanges makes files modified right before a snapshot clean, for
+// the tests of other packages that hash files right after committing them.
+func TrustRecentChanges(t *testing.T) {
+	t.Helper()
+
+	oldRacyWindow := racyWindow
+	racyWindow = 0
+	t.Cleanup(func() {
+		racyWindow = oldRacyWindow
+		mu.Lock()
+		defer mu.Unlock()
+		roots = map[string]string{}
+		snapshots = map[string]*snapshot{}
+	})
+}
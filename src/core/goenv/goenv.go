@@ -0,0 +1,74 @@
+// Package goenv reads the go env of the modules of a run, invoking the go
+// command once per module rather than once per directive.
+package goenv
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sync"
+
+	"github.com/oNaiPs/go-generate-fast/src/utils/fs"
+	"go.uber.org/zap"
+)
+
+// Env holds the go env variables used to identify generators.
+type Env struct {
+	// version of the go toolchain selected for the module, e.g. go1.24.1
+	GOVERSION string
+	// directory of the tools of the toolchain, run by `go tool <name>`
+	GOTOOLDIR string
+}
+
+type result struct {
+	once sync.Once
+	env  Env
+	err  error
+}
+
+var (
+	mu sync.Mutex
+	// results by module root, or by dir outside of modules
+	results = map[string]*result{}
+)
+
+// Get returns the go env of the module containing dir. The toolchain may
+// differ between modules, as selected by their go and toolchain directives.
+func Get(dir string) (Env, error) {
+	root := dir
+	if goMod := fs.FindGoMod(dir); goMod != "" {
+		root = filepath.Dir(goMod)
+	}
+
+	mu.Lock()
+	r, ok := results[root]
+	if !ok {
+		r = &result{}
+		results[root] = r
+	}
+	mu.Unlock()
+
+	r.once.Do(func() {
+		r.env, r.err = read(root)
+	})
+	return r.env, r.err
+}
+
+func read(dir string) (Env, error) {
+	cmd := exec.Command("go", "env", "-json", "GOVERSION", "GOTOOLDIR")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return Env{}, fmt.Errorf("cannot read go env: %w", err)
+	}
+
+	var env Env
+	err = json.Unmarshal(out, &env)
+	if err != nil {
+		return Env{}, fmt.Errorf("cannot read go env: %w", err)
+	}
+
+	zap.S().Debugf("Using %s for %s", env.GOVERSION, dir)
+	return env, nil
+}
@@ -0,0 +1,32 @@
+package goenv
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGet(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/mod\n"), 0600))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0700))
+
+	env, err := Get(dir)
+	assert.NoError(t, err)
+	assert.Regexp(t, `^go1\.`, env.GOVERSION)
+	assert.DirExists(t, env.GOTOOLDIR)
+	assert.Contains(t, env.GOTOOLDIR, runtime.GOOS+"_"+runtime.GOARCH)
+
+	// shared by the dirs of the module
+	mu.Lock()
+	r := results[dir]
+	mu.Unlock()
+	_, err = Get(filepath.Join(dir, "sub"))
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Same(t, r, results[dir])
+}
@@ -32,7 +32,9 @@ type PkgError struct {
 // TODO see alternative
 // https://github.com/uber-go/mock/blob/fcaca4af4e64b707bdb0773ec92441c524bce3d0/mockgen/mockgen.go#L836
 
-func ModulesAndErrors(args []string) []PkgError {
+// ModulesAndErrors lists the go files of the packages matching args, resolved
+// from dir. An empty dir is the current directory.
+func ModulesAndErrors(dir string, args []string) []PkgError {
 	if len(args) == 0 {
 		args = []string{"./..."}
 	}
@@ -40,6 +42,7 @@ func ModulesAndErrors(args []string) []PkgError {
 	cmd := exec.Command("go", append([]string{
 		"list", "-e", "-json=GoFiles,Dir,Incomplete,Error,DepsErrors",
 	}, args...)...)
+	cmd.Dir = dir
 
 	zap.S().Debug("Running command: ", strings.Join(cmd.Args, " "))
 
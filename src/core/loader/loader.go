@@ -0,0 +1,135 @@
+// Package loader memoizes the package metadata loaded during a run, so that
+// the directives of the same packages do not load them over and over.
+package loader
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/oNaiPs/go-generate-fast/src/core/golist"
+	"github.com/oNaiPs/go-generate-fast/src/utils/pkg"
+	"go.uber.org/zap"
+	"golang.org/x/tools/go/packages"
+)
+
+// Loader loads packages once per dir, patterns and tags. A nil Loader loads
+// them on every call.
+type Loader struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+type entry struct {
+	once    sync.Once
+	pkg     *packages.Package
+	goFiles []golist.PkgError
+	deps    []*packages.Package
+	err     error
+}
+
+// New returns an empty Loader, for a single run.
+func New() *Loader {
+	return &Loader{entries: map[string]*entry{}}
+}
+
+// Packages returns the package matching patterns, resolved from dir, like
+// pkg.LoadPackages.
+func (l *Loader) Packages(dir string, patterns []string, tags []string) *packages.Package {
+	if l == nil {
+		return pkg.LoadPackages(dir, patterns, tags)
+	}
+
+	e := l.get("packages", dir, patterns, tags)
+	e.once.Do(func() {
+		e.pkg = pkg.LoadPackages(dir, patterns, tags)
+	})
+	return e.pkg
+}
+
+// GoFiles returns the go files of the packages matching args, resolved from
+// dir, like golist.ModulesAndErrors.
+func (l *Loader) GoFiles(dir string, args []string) []golist.PkgError {
+	if l == nil {
+		return golist.ModulesAndErrors(dir, args)
+	}
+
+	e := l.get("golist", dir, args, nil)
+	e.once.Do(func() {
+		e.goFiles = golist.ModulesAndErrors(dir, args)
+	})
+	return e.goFiles
+}
+
+// Deps returns the packages matching patterns, resolved from dir with the
+// given go build flags, with their files, modules and transitive imports.
+func (l *Loader) Deps(dir string, patterns []string, buildFlags []string) ([]*packages.Package, error) {
+	if l == nil {
+		return loadDeps(dir, patterns, buildFlags)
+	}
+
+	e := l.get("deps", dir, patterns, buildFlags)
+	e.once.Do(func() {
+		e.deps, e.err = loadDeps(dir, patterns, buildFlags)
+	})
+	return e.deps, e.err
+}
+
+func loadDeps(dir string, patterns []string, buildFlags []string) ([]*packages.Package, error) {
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedEmbedFiles |
+			packages.NeedImports | packages.NeedDeps | packages.NeedModule,
+		BuildFlags: buildFlags,
+		Dir:        dir,
+	}
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return nil, err
+	}
+
+	var pkgErr error
+	packages.Visit(pkgs, nil, func(p *packages.Package) {
+		if pkgErr == nil && len(p.Errors) > 0 {
+			pkgErr = fmt.Errorf("%s: %s", p.PkgPath, p.Errors[0])
+		}
+	})
+	if pkgErr != nil {
+		return nil, pkgErr
+	}
+	return pkgs, nil
+}
+
+// Reset forgets the loaded packages. It must be called once the run changes
+// files, which may add or remove files from packages.
+func (l *Loader) Reset() {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = map[string]*entry{}
+}
+
+func (l *Loader) get(kind string, dir string, patterns []string, flags []string) *entry {
+	// an empty dir is the current dir, which is the same as its absolute path
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		absDir = dir
+	}
+	key := strings.Join([]string{kind, absDir, strings.Join(patterns, "\n"), strings.Join(flags, "\n")}, "\x00")
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.entries[key]
+	if !ok {
+		e = &entry{}
+		l.entries[key] = e
+	} else {
+		zap.S().Debugf("Reusing loaded packages %s from %s", strings.Join(patterns, " "), absDir)
+	}
+	return e
+}
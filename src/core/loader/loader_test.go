@@ -0,0 +1,67 @@
+package loader
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeModule(t *testing.T) string {
+	tempDir := t.TempDir()
+
+	err := os.WriteFile(path.Join(tempDir, "go.mod"), []byte("module example.com/mod"), 0644)
+	assert.NoError(t, err)
+	err = os.WriteFile(path.Join(tempDir, "file1.go"), []byte("package ex"), 0644)
+	assert.NoError(t, err)
+
+	return tempDir
+}
+
+func TestPackagesMemoized(t *testing.T) {
+	tempDir := writeModule(t)
+	l := New()
+
+	p := l.Packages(tempDir, []string{"."}, []string{})
+	assert.Equal(t, []string{path.Join(tempDir, "file1.go")}, p.CompiledGoFiles)
+
+	// same dir, patterns and tags
+	assert.Same(t, p, l.Packages(tempDir, []string{"."}, nil))
+	// different tags
+	assert.NotSame(t, p, l.Packages(tempDir, []string{"."}, []string{"tag1"}))
+
+	err := os.WriteFile(path.Join(tempDir, "file2.go"), []byte("package ex"), 0644)
+	assert.NoError(t, err)
+	assert.Same(t, p, l.Packages(tempDir, []string{"."}, nil))
+
+	l.Reset()
+	p = l.Packages(tempDir, []string{"."}, nil)
+	assert.Equal(t, []string{path.Join(tempDir, "file1.go"), path.Join(tempDir, "file2.go")}, p.CompiledGoFiles)
+}
+
+func TestGoFilesMemoized(t *testing.T) {
+	tempDir := writeModule(t)
+	l := New()
+
+	files := l.GoFiles(tempDir, []string{"."})
+	assert.Len(t, files, 1)
+	assert.Equal(t, path.Join(tempDir, "file1.go"), files[0].Package)
+
+	err := os.WriteFile(path.Join(tempDir, "file2.go"), []byte("package ex"), 0644)
+	assert.NoError(t, err)
+	assert.Len(t, l.GoFiles(tempDir, []string{"."}), 1)
+
+	l.Reset()
+	assert.Len(t, l.GoFiles(tempDir, []string{"."}), 2)
+}
+
+func TestNilLoader(t *testing.T) {
+	tempDir := writeModule(t)
+	var l *Loader
+
+	p := l.Packages(tempDir, []string{"."}, nil)
+	assert.Equal(t, []string{path.Join(tempDir, "file1.go")}, p.CompiledGoFiles)
+	assert.Len(t, l.GoFiles(tempDir, []string{"."}), 1)
+	l.Reset()
+}
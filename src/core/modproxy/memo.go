@@ -0,0 +1,141 @@
+package modproxy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/oNaiPs/go-generate-fast/src/core/config"
+	"go.uber.org/zap"
+)
+
+const (
+	fileName = "latest.json"
+	// bumped when the file format changes, discarding older files
+	version = 2
+)
+
+type result struct {
+	Module  string
+	Version string
+	// time the version was resolved, unix seconds
+	Resolved int64
+}
+
+type fileData struct {
+	Version int
+	// by package path and the proxies it was resolved with
+	Entries map[string]result
+}
+
+type memo struct {
+	mu      sync.Mutex
+	loaded  bool
+	entries map[string]result
+}
+
+var instance = &memo{}
+
+func filePath() string {
+	return filepath.Join(config.Get().ConfigDir, fileName)
+}
+
+// get returns the result resolved for a key within the configured TTL.
+func (m *memo) get(key string) (result, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.load()
+	r, ok := m.entries[key]
+	if !ok || time.Since(time.Unix(r.Resolved, 0)) >= config.Get().LatestTTL {
+		return result{}, false
+	}
+	return r, true
+}
+
+// set memoizes a result, and persists it when the TTL is not zero.
+func (m *memo) set(key string, r result) {
+	if config.Get().LatestTTL <= 0 {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.load()
+	r.Resolved = time.Now().Unix()
+	m.entries[key] = r
+
+	err := m.save()
+	if err != nil {
+		zap.S().Debugf("cannot save latest versions: %s", err)
+	}
+}
+
+// load reads the persisted entries once. A missing or unreadable file starts
+// an empty memo.
+func (m *memo) load() {
+	if m.loaded {
+		return
+	}
+	m.loaded = true
+	m.entries = map[string]result{}
+
+	data, err := os.ReadFile(filePath())
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	} else if err != nil {
+		zap.S().Debugf("cannot read latest versions: %s", err)
+		return
+	}
+
+	var fd fileData
+	err = json.Unmarshal(data, &fd)
+	if err != nil || fd.Version != version {
+		zap.S().Debugf("discarding latest versions: version %d, %v", fd.Version, err)
+		return
+	}
+	if fd.Entries != nil {
+		m.entries = fd.Entries
+	}
+}
+
+func (m *memo) save() error {
+	expired := time.Now().Add(-config.Get().LatestTTL).Unix()
+	for key, r := range m.entries {
+		if r.Resolved < expired {
+			delete(m.entries, key)
+		}
+	}
+
+	data, err := json.Marshal(fileData{Version: version, Entries: m.entries})
+	if err != nil {
+		return err
+	}
+
+	// concurrent runs may save at the same time, the last one wins
+	f, err := os.CreateTemp(config.Get().ConfigDir, fileName+".*")
+	if err != nil {
+		return fmt.Errorf("cannot write latest versions: %w", err)
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+
+	_, err = f.Write(data)
+	closeErr := f.Close()
+	if err != nil {
+		return fmt.Errorf("cannot write latest versions: %w", err)
+	}
+	if closeErr != nil {
+		return fmt.Errorf("cannot write latest versions: %w", closeErr)
+	}
+
+	err = os.Rename(f.Name(), filePath())
+	if err != nil {
+		return fmt.Errorf("cannot write latest versions: %w", err)
+	}
+	return nil
+}
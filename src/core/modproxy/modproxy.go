@@ -0,0 +1,289 @@
+// Package modproxy resolves the latest version of modules through the module
+// proxy protocol, like the go command does for `pkg@latest`, without
+// downloading them. Resolved versions are memoized on the config dir.
+package modproxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+var (
+	errNotFound = errors.New("not found")
+	// the module is fetched directly from its repository, by the go command
+	errNoProxy = errors.New("no proxy")
+)
+
+var client = &http.Client{Timeout: 30 * time.Second}
+
+// goEnv holds the go env variables that select where modules are queried.
+type goEnv struct {
+	GOPROXY   string
+	GONOPROXY string
+	GOPRIVATE string
+	GOFLAGS   string
+}
+
+// noProxy returns the patterns of the modules fetched without a proxy.
+func (env goEnv) noProxy() string {
+	if env.GONOPROXY != "" {
+		return env.GONOPROXY
+	}
+	return env.GOPRIVATE
+}
+
+// ResolveLatest returns the module providing a package and its latest
+// version, with the go env of dir. Like the go command, the longest module
+// path providing the package wins, and releases are preferred over
+// pre-releases. Retractions are not taken into account. Modules fetched
+// without a proxy are resolved by the go command.
+func ResolveLatest(dir string, pkgPath string) (string, string, error) {
+	env, err := readGoEnv(dir)
+	if err != nil {
+		return "", "", err
+	}
+
+	// versions differ between proxies
+	memoKey := strings.Join([]string{pkgPath, env.GOPROXY, env.noProxy()}, " ")
+	if r, ok := instance.get(memoKey); ok {
+		return r.Module, r.Version, nil
+	}
+
+	// runs of pkg@version ignore the main module, only vendor mode prevents
+	// them from querying modules
+	if modFlag(env.GOFLAGS) == "vendor" {
+		return "", "", fmt.Errorf("cannot query module due to -mod=vendor")
+	}
+
+	var lastErr error
+	var direct []string
+	for modPath := pkgPath; modPath != "." && modPath != "/"; modPath = path.Dir(modPath) {
+		if module.CheckPath(modPath) != nil {
+			continue
+		}
+
+		version, err := queryLatest(env, modPath)
+		if errors.Is(err, errNoProxy) {
+			direct = append(direct, modPath)
+			continue
+		}
+		if errors.Is(err, errNotFound) {
+			lastErr = err
+			continue
+		}
+		if err != nil {
+			return "", "", err
+		}
+
+		zap.S().Debugf("Resolved %s@latest to %s@%s", pkgPath, modPath, version)
+		instance.set(memoKey, result{Module: modPath, Version: version})
+		return modPath, version, nil
+	}
+
+	// not on the proxies, the go command fetches them from their repositories,
+	// which is much slower
+	for _, modPath := range direct {
+		version, err := goListLatest(dir, modPath)
+		if errors.Is(err, errNotFound) {
+			lastErr = err
+			continue
+		}
+		if err != nil {
+			return "", "", err
+		}
+
+		zap.S().Debugf("Resolved %s@latest to %s@%s with go list", pkgPath, modPath, version)
+		instance.set(memoKey, result{Module: modPath, Version: version})
+		return modPath, version, nil
+	}
+
+	if lastErr != nil {
+		return "", "", fmt.Errorf("no module provides package %s: %w", pkgPath, lastErr)
+	}
+	return "", "", fmt.Errorf("no module provides package %s", pkgPath)
+}
+
+func readGoEnv(dir string) (goEnv, error) {
+	cmd := exec.Command("go", "env", "-json", "GOPROXY", "GONOPROXY", "GOPRIVATE", "GOFLAGS")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return goEnv{}, fmt.Errorf("cannot read go env: %w", err)
+	}
+
+	var env goEnv
+	err = json.Unmarshal(out, &env)
+	if err != nil {
+		return goEnv{}, fmt.Errorf("cannot read go env: %w", err)
+	}
+	return env, nil
+}
+
+// modFlag returns the value of the -mod flag of GOFLAGS, the last one wins.
+func modFlag(goFlags string) string {
+	mod := ""
+	for _, flag := range strings.Fields(goFlags) {
+		if value, ok := strings.CutPrefix(strings.TrimLeft(flag, "-"), "mod="); ok {
+			mod = value
+		}
+	}
+	return mod
+}
+
+// goListLatest resolves the latest version of a module with the go command,
+// for the modules it fetches from their repositories. Any error is taken as
+// the module not existing, as the go command does not tell them apart.
+func goListLatest(dir string, modPath string) (string, error) {
+	cmd := exec.Command("go", "list", "-m", "-json", modPath+"@latest")
+	cmd.Dir = dir
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("go list -m %s@latest: %w: %s", modPath, errNotFound, strings.TrimSpace(stderr.String()))
+	}
+
+	var info struct{ Version string }
+	err = json.Unmarshal(out, &info)
+	if err != nil || !semver.IsValid(info.Version) {
+		return "", fmt.Errorf("invalid latest version of %s from go list", modPath)
+	}
+	return info.Version, nil
+}
+
+// queryLatest queries the proxies of GOPROXY in order. Proxies separated by a
+// comma are only skipped when they do not have the module, the ones separated
+// by a pipe on any error.
+func queryLatest(env goEnv, modPath string) (string, error) {
+	if module.MatchPrefixPatterns(env.noProxy(), modPath) {
+		return "", fmt.Errorf("%s matched by GONOPROXY: %w", modPath, errNoProxy)
+	}
+
+	proxies := env.GOPROXY
+	var lastErr error
+	for proxies != "" {
+		proxy, rest, _ := strings.Cut(proxies, ",")
+		fallbackOnAnyErr := false
+		if i := strings.Index(proxy, "|"); i >= 0 {
+			proxy, rest, fallbackOnAnyErr = proxies[:i], proxies[i+1:], true
+		}
+		proxies = rest
+
+		switch strings.TrimSpace(proxy) {
+		case "":
+			continue
+		case "off":
+			return "", fmt.Errorf("module lookup disabled by GOPROXY=off")
+		case "direct":
+			// reached when the previous proxies do not have the module
+			return "", fmt.Errorf("%s from GOPROXY=direct: %w", modPath, errNoProxy)
+		}
+
+		version, err := proxyLatest(strings.TrimSpace(proxy), modPath)
+		if err == nil {
+			return version, nil
+		}
+		lastErr = err
+		if !errors.Is(err, errNotFound) && !fallbackOnAnyErr {
+			return "", err
+		}
+	}
+
+	if lastErr != nil {
+		return "", lastErr
+	}
+	return "", fmt.Errorf("cannot resolve %s, GOPROXY is empty", modPath)
+}
+
+// proxyLatest returns the highest release of a module listed by a proxy, the
+// highest pre-release without releases, or the proxy's latest version
+// without tagged versions.
+func proxyLatest(proxy string, modPath string) (string, error) {
+	escaped, err := module.EscapePath(modPath)
+	if err != nil {
+		return "", err
+	}
+
+	list, err := fetch(proxy, escaped+"/@v/list")
+	if err != nil {
+		return "", err
+	}
+
+	latest := ""
+	for _, version := range strings.Fields(string(list)) {
+		if !semver.IsValid(version) {
+			continue
+		}
+		if latest == "" ||
+			isRelease(version) && !isRelease(latest) ||
+			isRelease(version) == isRelease(latest) && semver.Compare(version, latest) > 0 {
+			latest = version
+		}
+	}
+	if latest != "" {
+		return latest, nil
+	}
+
+	data, err := fetch(proxy, escaped+"/@latest")
+	if err != nil {
+		return "", err
+	}
+	var info struct{ Version string }
+	err = json.Unmarshal(data, &info)
+	if err != nil || !semver.IsValid(info.Version) {
+		return "", fmt.Errorf("invalid latest version of %s from %s", modPath, proxy)
+	}
+	return info.Version, nil
+}
+
+func isRelease(version string) bool {
+	return semver.Prerelease(version) == ""
+}
+
+// fetch reads a file of the proxy protocol from an http(s) or file:// proxy.
+func fetch(proxy string, name string) ([]byte, error) {
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GOPROXY %q: %w", proxy, err)
+	}
+
+	if u.Scheme == "file" {
+		data, err := os.ReadFile(filepath.Join(filepath.FromSlash(u.Path), filepath.FromSlash(name)))
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%s/%s: %w", proxy, name, errNotFound)
+		}
+		return data, err
+	}
+
+	resp, err := client.Get(strings.TrimSuffix(proxy, "/") + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return io.ReadAll(resp.Body)
+	case http.StatusNotFound, http.StatusGone:
+		return nil, fmt.Errorf("%s/%s: %w", proxy, name, errNotFound)
+	default:
+		return nil, fmt.Errorf("%s/%s: %s", proxy, name, resp.Status)
+	}
+}
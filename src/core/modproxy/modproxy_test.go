@@ -0,0 +1,214 @@
+package modproxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/oNaiPs/go-generate-fast/src/core/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "modproxy-test-")
+	if err != nil {
+		panic(err)
+	}
+	_ = os.Setenv("GO_GENERATE_FAST_DIR", dir)
+	config.Init()
+
+	code := m.Run()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func resetMemo(t *testing.T) {
+	t.Helper()
+
+	instance = &memo{}
+	_ = os.Remove(filePath())
+	t.Cleanup(func() {
+		instance = &memo{}
+	})
+}
+
+// writeProxy writes a file:// proxy listing the given versions of modules.
+func writeProxy(t *testing.T, dir string, versions map[string]string) {
+	t.Helper()
+
+	for escapedPath, list := range versions {
+		listFile := filepath.Join(dir, filepath.FromSlash(escapedPath), "@v", "list")
+		require.NoError(t, os.MkdirAll(filepath.Dir(listFile), 0700))
+		require.NoError(t, os.WriteFile(listFile, []byte(list), 0600))
+	}
+}
+
+func setProxy(t *testing.T, goProxy string) {
+	t.Helper()
+
+	t.Setenv("GOPROXY", goProxy)
+	t.Setenv("GONOPROXY", "")
+	t.Setenv("GOPRIVATE", "")
+	t.Setenv("GOFLAGS", "-mod=mod")
+}
+
+func TestResolveLatest(t *testing.T) {
+	resetMemo(t)
+
+	dir := t.TempDir()
+	writeProxy(t, dir, map[string]string{
+		"example.com/tool":       "v1.0.0\nv1.2.0\nv1.10.0\nv1.11.0-rc.1\n",
+		"example.com/!upper":     "v0.1.0\n",
+		"example.com/prerelease": "v0.1.0-alpha\nv0.2.0-beta\n",
+	})
+	setProxy(t, "file://"+filepath.ToSlash(dir))
+
+	modPath, version, err := ResolveLatest(".", "example.com/tool/cmd/tool")
+	assert.NoError(t, err)
+	assert.Equal(t, "example.com/tool", modPath)
+	assert.Equal(t, "v1.10.0", version)
+
+	modPath, version, err = ResolveLatest(".", "example.com/Upper")
+	assert.NoError(t, err)
+	assert.Equal(t, "example.com/Upper", modPath)
+	assert.Equal(t, "v0.1.0", version)
+
+	_, version, err = ResolveLatest(".", "example.com/prerelease")
+	assert.NoError(t, err)
+	assert.Equal(t, "v0.2.0-beta", version)
+
+	_, _, err = ResolveLatest(".", "example.com/missing/cmd")
+	assert.ErrorContains(t, err, "no module provides package example.com/missing/cmd")
+}
+
+func TestResolveLatestMemoized(t *testing.T) {
+	resetMemo(t)
+
+	dir := t.TempDir()
+	writeProxy(t, dir, map[string]string{"example.com/tool": "v1.0.0\n"})
+	setProxy(t, "file://"+filepath.ToSlash(dir))
+
+	_, version, err := ResolveLatest(".", "example.com/tool")
+	assert.NoError(t, err)
+	assert.Equal(t, "v1.0.0", version)
+
+	// reused within the TTL, across runs
+	writeProxy(t, dir, map[string]string{"example.com/tool": "v1.0.0\nv1.1.0\n"})
+	instance = &memo{}
+	_, version, err = ResolveLatest(".", "example.com/tool")
+	assert.NoError(t, err)
+	assert.Equal(t, "v1.0.0", version)
+
+	// resolved again once expired
+	oldTTL := config.Get().LatestTTL
+	config.Get().LatestTTL = 0
+	t.Cleanup(func() {
+		config.Get().LatestTTL = oldTTL
+	})
+	_, version, err = ResolveLatest(".", "example.com/tool")
+	assert.NoError(t, err)
+	assert.Equal(t, "v1.1.0", version)
+
+	config.Get().LatestTTL = time.Hour
+	_, version, err = ResolveLatest(".", "example.com/tool")
+	assert.NoError(t, err)
+	assert.Equal(t, "v1.0.0", version)
+}
+
+func TestResolveLatestProxyList(t *testing.T) {
+	resetMemo(t)
+
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		switch r.URL.Path {
+		case "/example.com/tool/@v/list":
+			_, _ = w.Write([]byte("v2.0.0\n"))
+		case "/example.com/broken/@v/list":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	writeProxy(t, dir, map[string]string{"example.com/broken": "v1.0.0\n"})
+
+	// not found falls back to the next proxy
+	setProxy(t, "file://"+filepath.ToSlash(t.TempDir())+","+server.URL)
+	_, version, err := ResolveLatest(".", "example.com/tool")
+	assert.NoError(t, err)
+	assert.Equal(t, "v2.0.0", version)
+
+	// other errors only with a pipe
+	setProxy(t, server.URL+",file://"+filepath.ToSlash(dir))
+	_, _, err = ResolveLatest(".", "example.com/broken")
+	assert.ErrorContains(t, err, "500")
+
+	setProxy(t, server.URL+"|file://"+filepath.ToSlash(dir))
+	_, version, err = ResolveLatest(".", "example.com/broken")
+	assert.NoError(t, err)
+	assert.Equal(t, "v1.0.0", version)
+	assert.Contains(t, requests, "/example.com/broken/@v/list")
+}
+
+func TestResolveLatestDisabled(t *testing.T) {
+	resetMemo(t)
+
+	setProxy(t, "off")
+	_, _, err := ResolveLatest(".", "example.com/tool")
+	assert.ErrorContains(t, err, "GOPROXY=off")
+
+	// resolved by the go command, which cannot reach example.com either
+	setProxy(t, "direct")
+	_, _, err = ResolveLatest(".", "example.com/tool")
+	assert.ErrorContains(t, err, "go list -m example.com@latest")
+
+	dir := t.TempDir()
+	writeProxy(t, dir, map[string]string{"example.com/tool": "v1.0.0\n"})
+	setProxy(t, "file://"+filepath.ToSlash(dir))
+
+	t.Setenv("GOPRIVATE", "example.com")
+	_, _, err = ResolveLatest(".", "example.com/tool")
+	assert.ErrorContains(t, err, "go list -m example.com@latest")
+
+	t.Setenv("GOPRIVATE", "")
+	t.Setenv("GOFLAGS", "-mod=vendor")
+	_, _, err = ResolveLatest(".", "example.com/tool")
+	assert.ErrorContains(t, err, "-mod=vendor")
+
+	// the last flag wins
+	t.Setenv("GOFLAGS", "-mod=vendor --mod=readonly")
+	_, version, err := ResolveLatest(".", "example.com/tool")
+	assert.NoError(t, err)
+	assert.Equal(t, "v1.0.0", version)
+}
+
+func TestResolveLatestMemoizedByProxy(t *testing.T) {
+	resetMemo(t)
+
+	dir1 := t.TempDir()
+	writeProxy(t, dir1, map[string]string{"example.com/tool": "v1.0.0\n"})
+	dir2 := t.TempDir()
+	writeProxy(t, dir2, map[string]string{"example.com/tool": "v2.0.0\n"})
+
+	setProxy(t, "file://"+filepath.ToSlash(dir1))
+	_, version, err := ResolveLatest(".", "example.com/tool")
+	assert.NoError(t, err)
+	assert.Equal(t, "v1.0.0", version)
+
+	setProxy(t, "file://"+filepath.ToSlash(dir2))
+	_, version, err = ResolveLatest(".", "example.com/tool")
+	assert.NoError(t, err)
+	assert.Equal(t, "v2.0.0", version)
+
+	// the other proxies are not queried
+	t.Setenv("GOPRIVATE", "example.com")
+	_, _, err = ResolveLatest(".", "example.com/tool")
+	assert.Error(t, err)
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/oNaiPs/go-generate-fast/src/core/storage"
+	"go.uber.org/zap"
+)
+
+// Server serves a cache directory over HTTP, with the protocol expected by
+// storage.HTTPStorage: GET, PUT and HEAD requests on /<key>/<name>.
+type Server struct {
+	Storage *storage.DirStorage
+	// when not empty, requests must have a matching "Authorization: Bearer" header
+	Token string
+	// rejects uploads
+	ReadOnly bool
+}
+
+func New(dir string) *Server {
+	return &Server{Storage: storage.NewDir(dir)}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	zap.S().Debugf("%s %s", r.Method, r.URL.Path)
+
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// the served dir may be a live local cache, its locks, stamps and other
+	// dirs are not exposed
+	key, name, ok := splitPath(r.URL.Path)
+	if !ok || storage.ValidatePath(key, name) != nil || !storage.IsEntryKey(key) || !storage.IsEntryFile(name) {
+		http.Error(w, "invalid path", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodHead:
+		exists, err := s.Storage.Exists(key, name)
+		if err != nil {
+			s.error(w, err)
+		} else if !exists {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case http.MethodGet:
+		exists, err := s.Storage.Exists(key, name)
+		if err != nil {
+			s.error(w, err)
+			return
+		} else if !exists {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		err = s.Storage.Get(key, name, w)
+		if err != nil {
+			zap.S().Errorf("cannot send %s/%s: %s", key, name, err)
+		}
+	case http.MethodPut:
+		if s.ReadOnly {
+			http.Error(w, "read-only cache", http.StatusForbidden)
+			return
+		}
+		if name == storage.CompleteFileName {
+			exists, err := s.Storage.Exists(key, storage.ConfigFileName)
+			if err != nil {
+				s.error(w, err)
+				return
+			} else if !exists {
+				http.Error(w, "entry has no config", http.StatusConflict)
+				return
+			}
+		}
+		err := s.Storage.Put(key, name, r.Body)
+		if errors.Is(err, storage.ErrCorruptBlob) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			s.error(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	if s.Token == "" {
+		return true
+	}
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return found && subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) == 1
+}
+
+func (s *Server) error(w http.ResponseWriter, err error) {
+	if errors.Is(err, storage.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	zap.S().Error(err)
+	http.Error(w, "internal error", http.StatusInternalServerError)
+}
+
+// splitPath splits /a/bc/def/name into the entry key and the file name.
+func splitPath(urlPath string) (string, string, bool) {
+	p := strings.TrimPrefix(urlPath, "/")
+	idx := strings.LastIndex(p, "/")
+	if idx <= 0 {
+		return "", "", false
+	}
+	return p[:idx], p[idx+1:], true
+}
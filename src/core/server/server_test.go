@@ -0,0 +1,151 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oNaiPs/go-generate-fast/src/core/storage"
+	"github.com/oNaiPs/go-generate-fast/src/utils/hash"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestServer(t *testing.T, s *Server) *storage.HTTPStorage {
+	t.Helper()
+
+	httpServer := httptest.NewServer(s)
+	t.Cleanup(httpServer.Close)
+
+	return storage.NewHTTP(httpServer.URL, time.Second)
+}
+
+func TestServer(t *testing.T) {
+	dir := t.TempDir()
+	client := newTestServer(t, New(dir))
+
+	exists, err := client.Exists("a/bc/def", "cache.json")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	var buf bytes.Buffer
+	err = client.Get("a/bc/def", "cache.json", &buf)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	err = client.Put("a/bc/def", "cache.json", strings.NewReader("{}"))
+	assert.NoError(t, err)
+
+	// files shall be laid out like the local cache
+	data, err := os.ReadFile(path.Join(dir, "a", "bc", "def", "cache.json"))
+	assert.NoError(t, err)
+	assert.Equal(t, "{}", string(data))
+
+	exists, err = client.Exists("a/bc/def", "cache.json")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	err = client.Get("a/bc/def", "cache.json", &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "{}", buf.String())
+}
+
+func TestServerToken(t *testing.T) {
+	s := New(t.TempDir())
+	s.Token = "secret"
+	client := newTestServer(t, s)
+
+	_, err := client.Exists("a/bc/def", "cache.json")
+	assert.ErrorContains(t, err, "401 Unauthorized")
+
+	client.Token = "wrong"
+	err = client.Put("a/bc/def", "cache.json", strings.NewReader("{}"))
+	assert.ErrorContains(t, err, "401 Unauthorized")
+
+	client.Token = "secret"
+	err = client.Put("a/bc/def", "cache.json", strings.NewReader("{}"))
+	assert.NoError(t, err)
+}
+
+func TestServerReadOnly(t *testing.T) {
+	dir := t.TempDir()
+	s := New(dir)
+	s.ReadOnly = true
+	client := newTestServer(t, s)
+
+	err := client.Put("a/bc/def", "cache.json", strings.NewReader("{}"))
+	assert.ErrorContains(t, err, "403 Forbidden")
+	assert.NoFileExists(t, path.Join(dir, "a", "bc", "def", "cache.json"))
+
+	assert.NoError(t, os.MkdirAll(path.Join(dir, "a", "bc", "def"), 0700))
+	assert.NoError(t, os.WriteFile(path.Join(dir, "a", "bc", "def", "cache.json"), []byte("{}"), 0600))
+
+	var buf bytes.Buffer
+	err = client.Get("a/bc/def", "cache.json", &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "{}", buf.String())
+}
+
+func TestServerInvalidRequests(t *testing.T) {
+	s := New(t.TempDir())
+
+	for _, tc := range []struct {
+		method string
+		path   string
+		status int
+	}{
+		{http.MethodGet, "/cache.json", http.StatusBadRequest},
+		{http.MethodGet, "/a/bc/../../cache.json", http.StatusBadRequest},
+		{http.MethodGet, "/a/bc/def/", http.StatusBadRequest},
+		{http.MethodDelete, "/a/bc/def/cache.json", http.StatusMethodNotAllowed},
+		// not entries of the served cache
+		{http.MethodPut, "/locks/a/bc/def.lock", http.StatusBadRequest},
+		{http.MethodPut, "/quarantine/abcdef/cache.json", http.StatusBadRequest},
+		{http.MethodPut, "/blobs/" + strings.Repeat("a", 2) + "/" + strings.Repeat("a", 64), http.StatusBadRequest},
+		{http.MethodPut, "/a/bc/def/restored", http.StatusBadRequest},
+		{http.MethodPut, "/a/bc/def/.cache.json.tmp", http.StatusBadRequest},
+		{http.MethodPut, "/A/BC/DEF/cache.json", http.StatusBadRequest},
+		{http.MethodPut, "/a/bc/def/ghi/cache.json", http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest(tc.method, "/", nil)
+		req.URL.Path = tc.path
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		assert.Equal(t, tc.status, rec.Code, "%s %s", tc.method, tc.path)
+	}
+}
+
+func TestServerBlobs(t *testing.T) {
+	dir := t.TempDir()
+	client := newTestServer(t, New(dir))
+
+	name, err := hash.HashString("content")
+	assert.NoError(t, err)
+
+	err = client.Put("a/bc/def", name, strings.NewReader("other content"))
+	assert.ErrorContains(t, err, "400 Bad Request")
+	assert.NoFileExists(t, storage.BlobPath(dir, name))
+
+	err = client.Put("a/bc/def", name, strings.NewReader("content"))
+	assert.NoError(t, err)
+	assert.FileExists(t, storage.BlobPath(dir, name))
+
+	// the hash is the one of the uncompressed content
+	err = client.Put("a/bc/def", name+".gz", strings.NewReader("content"))
+	assert.ErrorContains(t, err, "400 Bad Request")
+}
+
+func TestServerCompleteWithoutConfig(t *testing.T) {
+	dir := t.TempDir()
+	client := newTestServer(t, New(dir))
+
+	err := client.Put("a/bc/def", "complete", strings.NewReader(""))
+	assert.ErrorContains(t, err, "409 Conflict")
+	assert.NoFileExists(t, path.Join(dir, "a", "bc", "def", "complete"))
+
+	assert.NoError(t, client.Put("a/bc/def", "cache.json", strings.NewReader("{}")))
+	assert.NoError(t, client.Put("a/bc/def", "complete", strings.NewReader("")))
+}
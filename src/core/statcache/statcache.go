@@ -0,0 +1,202 @@
+// Package statcache memoizes the hashes of files, so that unchanged inputs are
+// not re-read on every run. Hashes are keyed by the file path and the stat
+// attributes that change with its content, and persisted on the config dir.
+package statcache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/oNaiPs/go-generate-fast/src/core/config"
+	"github.com/oNaiPs/go-generate-fast/src/utils/fs"
+	"github.com/oNaiPs/go-generate-fast/src/utils/hash"
+	"go.uber.org/zap"
+)
+
+const (
+	fileName = "statcache.json"
+	// bumped when the hashes or the file format change, discarding older files
+	version = 1
+	// entries not used for this long are dropped on save
+	maxAge = 30 * 24 * time.Hour
+	// used times are only refreshed with this precision, to avoid rewriting
+	// the file on every run
+	usedPrecision = 24 * time.Hour
+)
+
+// files modified this close to their hashing are not memoized, as a later
+// modification within the timestamp granularity would go unnoticed
+var racyWindow = 2 * time.Second
+
+type entry struct {
+	fs.FileStat
+	Hash string
+	// last time the entry was used, unix seconds
+	Used int64
+}
+
+type fileData struct {
+	Version int
+	Entries map[string]entry
+}
+
+type memo struct {
+	mu      sync.Mutex
+	loaded  bool
+	entries map[string]entry
+	dirty   bool
+}
+
+var instance = &memo{}
+
+// HashFile returns the hash of a file, reusing the memoized one when the file
+// did not change since it was hashed.
+func HashFile(file string) (string, error) {
+	absPath, err := filepath.Abs(file)
+	if err != nil {
+		return "", err
+	}
+
+	stat, err := fs.Stat(absPath)
+	if err != nil {
+		return "", err
+	}
+
+	if h, ok := instance.get(absPath, stat); ok {
+		return h, nil
+	}
+
+	start := time.Now()
+	h, err := hash.HashFile(absPath)
+	if err != nil {
+		return "", err
+	}
+
+	if !isRacy(stat, start) {
+		instance.set(absPath, entry{FileStat: stat, Hash: h})
+	}
+	return h, nil
+}
+
+// isRacy reports whether the file may have been modified right before or
+// while it was hashed, without its stat attributes changing afterwards.
+func isRacy(stat fs.FileStat, hashedAt time.Time) bool {
+	changed := max(stat.ModTime, stat.ChangeTime)
+	return changed >= hashedAt.Add(-racyWindow).UnixNano()
+}
+
+// Save persists the memoized hashes, when any changed.
+func Save() error {
+	return instance.save()
+}
+
+func filePath() string {
+	return filepath.Join(config.Get().ConfigDir, fileName)
+}
+
+func (m *memo) get(path string, stat fs.FileStat) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.load()
+	e, ok := m.entries[path]
+	if !ok || e.FileStat != stat {
+		return "", false
+	}
+
+	now := time.Now()
+	if now.Sub(time.Unix(e.Used, 0)) > usedPrecision {
+		e.Used = now.Unix()
+		m.entries[path] = e
+		m.dirty = true
+	}
+	return e.Hash, true
+}
+
+func (m *memo) set(path string, e entry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.load()
+	e.Used = time.Now().Unix()
+	m.entries[path] = e
+	m.dirty = true
+}
+
+// load reads the persisted entries once. A missing or unreadable file starts
+// an empty memo.
+func (m *memo) load() {
+	if m.loaded {
+		return
+	}
+	m.loaded = true
+	m.entries = map[string]entry{}
+
+	data, err := os.ReadFile(filePath())
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	} else if err != nil {
+		zap.S().Debugf("cannot read stat cache: %s", err)
+		return
+	}
+
+	var fd fileData
+	err = json.Unmarshal(data, &fd)
+	if err != nil || fd.Version != version {
+		zap.S().Debugf("discarding stat cache: version %d, %v", fd.Version, err)
+		return
+	}
+	if fd.Entries != nil {
+		m.entries = fd.Entries
+	}
+}
+
+func (m *memo) save() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.dirty {
+		return nil
+	}
+
+	expired := time.Now().Add(-maxAge).Unix()
+	for path, e := range m.entries {
+		if e.Used < expired {
+			delete(m.entries, path)
+		}
+	}
+
+	data, err := json.Marshal(fileData{Version: version, Entries: m.entries})
+	if err != nil {
+		return err
+	}
+
+	// concurrent runs may save at the same time, the last one wins
+	f, err := os.CreateTemp(config.Get().ConfigDir, fileName+".*")
+	if err != nil {
+		return fmt.Errorf("cannot write stat cache: %w", err)
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+
+	_, err = f.Write(data)
+	closeErr := f.Close()
+	if err != nil {
+		return fmt.Errorf("cannot write stat cache: %w", err)
+	}
+	if closeErr != nil {
+		return fmt.Errorf("cannot write stat cache: %w", closeErr)
+	}
+
+	err = os.Rename(f.Name(), filePath())
+	if err != nil {
+		return fmt.Errorf("cannot write stat cache: %w", err)
+	}
+
+	m.dirty = false
+	return nil
+}
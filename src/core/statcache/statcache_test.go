@@ -0,0 +1,138 @@
+package statcache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/oNaiPs/go-generate-fast/src/core/config"
+	util_test "github.com/oNaiPs/go-generate-fast/src/test"
+	"github.com/oNaiPs/go-generate-fast/src/utils/hash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "statcache-test-")
+	if err != nil {
+		panic(err)
+	}
+	_ = os.Setenv("GO_GENERATE_FAST_DIR", dir)
+	config.Init()
+
+	code := m.Run()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func resetMemo(t *testing.T) {
+	t.Helper()
+
+	instance = &memo{}
+	_ = os.Remove(filePath())
+	t.Cleanup(func() {
+		instance = &memo{}
+	})
+}
+
+// disableRacyWindow memoizes the files just written by the test.
+func disableRacyWindow(t *testing.T) {
+	t.Helper()
+
+	oldRacyWindow := racyWindow
+	racyWindow = 0
+	t.Cleanup(func() {
+		racyWindow = oldRacyWindow
+	})
+}
+
+func TestHashFile(t *testing.T) {
+	resetMemo(t)
+	disableRacyWindow(t)
+
+	file := util_test.WriteTempFile(t, "content")
+	expected, err := hash.HashFile(file.Name())
+	require.NoError(t, err)
+
+	h, err := HashFile(file.Name())
+	require.NoError(t, err)
+	assert.Equal(t, expected, h)
+	assert.Contains(t, instance.entries, file.Name())
+
+	// persisted across runs
+	require.NoError(t, Save())
+	instance = &memo{}
+
+	h, err = HashFile(file.Name())
+	require.NoError(t, err)
+	assert.Equal(t, expected, h)
+	assert.Equal(t, expected, instance.entries[file.Name()].Hash)
+	assert.False(t, instance.dirty)
+
+	_, err = HashFile(filepath.Join(t.TempDir(), "missing"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestHashFileChanged(t *testing.T) {
+	resetMemo(t)
+	disableRacyWindow(t)
+
+	file := util_test.WriteTempFile(t, "content")
+	_, err := HashFile(file.Name())
+	require.NoError(t, err)
+
+	// same size and modification time, only the change time tells them apart
+	modTime := time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, os.WriteFile(file.Name(), []byte("CONTENT"), 0600))
+	require.NoError(t, os.Chtimes(file.Name(), modTime, modTime))
+
+	expected, err := hash.HashFile(file.Name())
+	require.NoError(t, err)
+
+	h, err := HashFile(file.Name())
+	require.NoError(t, err)
+	assert.Equal(t, expected, h)
+}
+
+func TestHashFileRacy(t *testing.T) {
+	resetMemo(t)
+
+	// just modified, a later change may keep the same times
+	file := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(file, []byte("content"), 0600))
+
+	_, err := HashFile(file)
+	require.NoError(t, err)
+	assert.NotContains(t, instance.entries, file)
+}
+
+func TestSaveExpired(t *testing.T) {
+	resetMemo(t)
+	disableRacyWindow(t)
+
+	file := util_test.WriteTempFile(t, "content")
+	_, err := HashFile(file.Name())
+	require.NoError(t, err)
+
+	instance.entries["/old"] = entry{Hash: "old", Used: time.Now().Add(-maxAge - time.Hour).Unix()}
+	require.NoError(t, Save())
+
+	instance = &memo{}
+	instance.load()
+	assert.Contains(t, instance.entries, file.Name())
+	assert.NotContains(t, instance.entries, "/old")
+}
+
+func TestLoadInvalid(t *testing.T) {
+	resetMemo(t)
+
+	require.NoError(t, os.WriteFile(filePath(), []byte(`{"Version":0,"Entries":{"/a":{}}}`), 0600))
+	instance.load()
+	assert.Empty(t, instance.entries)
+
+	instance = &memo{}
+	require.NoError(t, os.WriteFile(filePath(), []byte("{"), 0600))
+	instance.load()
+	assert.Empty(t, instance.entries)
+}
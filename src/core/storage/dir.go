@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DirStorage stores entries on a local directory, laid out the same way as
+// the local cache: <root>/<key>/<name>, with blobs shared by all entries
+// under <root>/blobs.
+type DirStorage struct {
+	Root string
+}
+
+func NewDir(root string) *DirStorage {
+	return &DirStorage{Root: root}
+}
+
+func (s *DirStorage) Name() string {
+	return "dir:" + s.Root
+}
+
+func (s *DirStorage) path(key string, name string) (string, error) {
+	if err := ValidatePath(key, name); err != nil {
+		return "", err
+	}
+
+	entryPath := filepath.Join(s.Root, filepath.FromSlash(key), name)
+	if !IsBlob(name) {
+		return entryPath, nil
+	}
+
+	// entries written before blobs were shared keep them on the entry dir
+	if _, err := os.Stat(entryPath); err == nil {
+		return entryPath, nil
+	}
+	return BlobPath(s.Root, name), nil
+}
+
+func (s *DirStorage) Exists(key string, name string) (bool, error) {
+	p, err := s.path(key, name)
+	if err != nil {
+		return false, err
+	}
+
+	info, err := os.Stat(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return info.Mode().IsRegular(), nil
+}
+
+func (s *DirStorage) Get(key string, name string, w io.Writer) error {
+	p, err := s.path(key, name)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	_, err = io.Copy(w, f)
+	return err
+}
+
+func (s *DirStorage) Put(key string, name string, r io.Reader) error {
+	p, err := s.path(key, name)
+	if err != nil {
+		return err
+	}
+
+	// readable by the other users of a shared mount, as allowed by the umask
+	err = os.MkdirAll(filepath.Dir(p), 0755)
+	if err != nil {
+		return fmt.Errorf("cannot create entry dir: %w", err)
+	}
+
+	// write to a temp file first so that readers never see partial files
+	tmpFile, err := os.OpenFile(
+		filepath.Join(filepath.Dir(p), fmt.Sprintf(".%s.%d.tmp", name, time.Now().UnixNano())),
+		os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmpFile.Name()) }()
+
+	_, err = io.Copy(tmpFile, r)
+	if err != nil {
+		_ = tmpFile.Close()
+		return err
+	}
+
+	err = tmpFile.Close()
+	if err != nil {
+		return err
+	}
+
+	// blobs are shared by all entries, a bad one would corrupt all of them
+	if IsBlob(name) {
+		err = VerifyBlob(tmpFile.Name(), name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return os.Rename(tmpFile.Name(), p)
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HTTPStorage stores entries on a cache server.
+// Entry files are accessed with GET, PUT and HEAD requests on <url>/<key>/<name>.
+type HTTPStorage struct {
+	URL string
+	// optional bearer token sent on every request
+	Token  string
+	client *http.Client
+}
+
+func NewHTTP(url string, timeout time.Duration) *HTTPStorage {
+	return &HTTPStorage{
+		URL:    strings.TrimSuffix(url, "/"),
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (s *HTTPStorage) Name() string {
+	return s.URL
+}
+
+func (s *HTTPStorage) request(method string, key string, name string, body io.Reader) (*http.Response, error) {
+	if err := ValidatePath(key, name); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(method, s.URL+"/"+key+"/"+name, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.Token)
+	}
+
+	return s.client.Do(req)
+}
+
+func (s *HTTPStorage) Exists(key string, name string) (bool, error) {
+	resp, err := s.request(http.MethodHead, key, name, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("unexpected status checking %s/%s: %s", key, name, resp.Status)
+	}
+}
+
+func (s *HTTPStorage) Get(key string, name string, w io.Writer) error {
+	resp, err := s.request(http.MethodGet, key, name, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		_, err = io.Copy(w, resp.Body)
+		return err
+	case http.StatusNotFound:
+		return ErrNotFound
+	default:
+		return fmt.Errorf("unexpected status downloading %s/%s: %s", key, name, resp.Status)
+	}
+}
+
+func (s *HTTPStorage) Put(key string, name string, r io.Reader) error {
+	resp, err := s.request(http.MethodPut, key, name, r)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status uploading %s/%s: %s", key, name, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/oNaiPs/go-generate-fast/src/utils/compress"
+	"github.com/oNaiPs/go-generate-fast/src/utils/copy"
+)
+
+// ErrNotFound is returned when the requested entry file does not exist on the storage.
+var ErrNotFound = errors.New("not found")
+
+// ErrCorruptBlob is returned when the content put as a blob does not match its hash.
+var ErrCorruptBlob = errors.New("blob content does not match its hash")
+
+// BlobsDirName is the directory, under a cache root, holding the output file
+// blobs shared by all entries.
+const BlobsDirName = "blobs"
+
+const (
+	// ConfigFileName is the entry file describing the entry and its blobs.
+	ConfigFileName = "cache.json"
+	// CompleteFileName is the entry file written last, entries without it are
+	// incomplete.
+	CompleteFileName = "complete"
+)
+
+// Storage is a backend holding cache entries.
+// Entries are addressed by their key, the entry directory relative to the
+// cache root (e.g. "a/bc/defg..."), and are made of named files: the
+// cache.json config plus the hash-named blobs of the output files.
+type Storage interface {
+	Name() string
+	// Exists reports whether the entry has a file with the given name.
+	Exists(key string, name string) (bool, error)
+	// Get writes the content of an entry file to w.
+	Get(key string, name string, w io.Writer) error
+	// Put stores the content read from r as an entry file.
+	Put(key string, name string, r io.Reader) error
+}
+
+type Options struct {
+	// timeout of remote requests
+	Timeout time.Duration
+	// bearer token sent to cache servers
+	Token string
+}
+
+// New creates a storage from the given url.
+// Supported schemes are http(s):// for a cache server and file:// for a
+// (possibly network mounted) cache directory.
+func New(rawURL string, opts Options) (Storage, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse storage url: %w", err)
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		s := NewHTTP(rawURL, opts.Timeout)
+		s.Token = opts.Token
+		return s, nil
+	case "file":
+		return NewDir(u.Path), nil
+	default:
+		return nil, fmt.Errorf("unsupported storage url scheme %q", u.Scheme)
+	}
+}
+
+// ValidatePath makes sure that key and name cannot be used to escape the storage root.
+func ValidatePath(key string, name string) error {
+	if key == "" || name == "" {
+		return errors.New("empty key or name")
+	}
+	if strings.Contains(name, "/") || strings.Contains(name, "\\") || strings.Contains(key, "\\") {
+		return fmt.Errorf("invalid entry path %q", key+"/"+name)
+	}
+	if path.IsAbs(key) || path.Clean(key) != key {
+		return fmt.Errorf("invalid entry key %q", key)
+	}
+	for _, elem := range strings.Split(key+"/"+name, "/") {
+		if elem == "." || elem == ".." {
+			return fmt.Errorf("invalid entry path %q", key+"/"+name)
+		}
+	}
+	return nil
+}
+
+// IsEntryKey reports whether a key has the layout of the entry keys,
+// <1 hex char>/<2 hex chars>/<hex chars>, rather than being one of the other
+// dirs of a cache root.
+func IsEntryKey(key string) bool {
+	elems := strings.Split(key, "/")
+	if len(elems) != 3 || len(elems[0]) != 1 || len(elems[1]) != 2 || elems[2] == "" {
+		return false
+	}
+	for _, elem := range elems {
+		if !isHex(elem) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsEntryFile reports whether name is one of the files of an entry: its
+// config, its complete marker or one of its blobs.
+func IsEntryFile(name string) bool {
+	return name == ConfigFileName || name == CompleteFileName || IsBlob(name)
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// IsBlob reports whether an entry file name is an output file blob: the hash
+// of the file, with the extension of its compression codec if any.
+func IsBlob(name string) bool {
+	if len(name) < 64 || !isHex(name[:64]) {
+		return false
+	}
+
+	_, ok := blobCodec(name)
+	return ok
+}
+
+// blobCodec returns the compression codec of a blob, from its extension.
+func blobCodec(name string) (string, bool) {
+	for _, codec := range []string{compress.None, compress.Gzip} {
+		if name[64:] == compress.Extension(codec) {
+			return codec, true
+		}
+	}
+	return "", false
+}
+
+// VerifyBlob returns ErrCorruptBlob when the content of a blob file does not
+// match the hash in its name.
+func VerifyBlob(file string, name string) error {
+	codec, ok := blobCodec(name)
+	if !ok {
+		return fmt.Errorf("invalid blob name %q", name)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	r, err := compress.NewReader(f, codec)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrCorruptBlob, err)
+	}
+	defer func() { _ = r.Close() }()
+
+	hash, err := copy.CopyHash(io.Discard, r)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrCorruptBlob, err)
+	}
+	if hash != name[:64] {
+		return ErrCorruptBlob
+	}
+	return nil
+}
+
+// BlobPath returns where a blob is stored under a cache root.
+// Blobs are addressed by their content hash only, so that identical output
+// files of different entries are stored once: <root>/blobs/<2 hex chars>/<name>.
+func BlobPath(root string, name string) string {
+	return filepath.Join(root, BlobsDirName, name[:2], name)
+}
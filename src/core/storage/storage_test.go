@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oNaiPs/go-generate-fast/src/utils/hash"
+	"github.com/stretchr/testify/assert"
+)
+
+// fileServer is a minimal cache server backed by a DirStorage.
+func fileServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	dir := NewDir(t.TempDir())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := strings.TrimPrefix(r.URL.Path, "/")
+		idx := strings.LastIndex(p, "/")
+		key, name := p[:idx], p[idx+1:]
+
+		switch r.Method {
+		case http.MethodHead:
+			exists, _ := dir.Exists(key, name)
+			if !exists {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		case http.MethodGet:
+			var buf bytes.Buffer
+			if err := dir.Get(key, name, &buf); err != nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = io.Copy(w, &buf)
+		case http.MethodPut:
+			if err := dir.Put(key, name, r.Body); err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func testStorage(t *testing.T, s Storage) {
+	exists, err := s.Exists("a/bc/def", "cache.json")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	var buf bytes.Buffer
+	err = s.Get("a/bc/def", "cache.json", &buf)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	err = s.Put("a/bc/def", "cache.json", strings.NewReader("content"))
+	assert.NoError(t, err)
+
+	exists, err = s.Exists("a/bc/def", "cache.json")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	err = s.Get("a/bc/def", "cache.json", &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "content", buf.String())
+
+	err = s.Put("../bc/def", "cache.json", strings.NewReader("content"))
+	assert.ErrorContains(t, err, "invalid entry")
+}
+
+func TestDirStorage(t *testing.T) {
+	testStorage(t, NewDir(t.TempDir()))
+}
+
+func TestDirStorageBlobs(t *testing.T) {
+	root := t.TempDir()
+	s := NewDir(root)
+	name, err := hash.HashString("content")
+	assert.NoError(t, err)
+
+	// the content is checked against the hash before it is stored
+	err = s.Put("a/bc/def", name, strings.NewReader("other content"))
+	assert.ErrorIs(t, err, ErrCorruptBlob)
+	assert.NoFileExists(t, BlobPath(root, name))
+
+	err = s.Put("a/bc/def", name, strings.NewReader("content"))
+	assert.NoError(t, err)
+	assert.FileExists(t, BlobPath(root, name))
+	assert.NoFileExists(t, filepath.Join(root, "a", "bc", "def", name))
+
+	// blobs are shared by all entries
+	var buf bytes.Buffer
+	err = s.Get("0/12/345", name, &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "content", buf.String())
+
+	// entries written before blobs were shared
+	legacyHash := strings.Repeat("cd", 32)
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "a", "bc", "def"), 0700))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "a", "bc", "def", legacyHash), []byte("legacy"), 0600))
+	exists, err := s.Exists("a/bc/def", legacyHash)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestDirStoragePermissions(t *testing.T) {
+	root := t.TempDir()
+	s := NewDir(root)
+
+	err := s.Put("a/bc/def", "cache.json", strings.NewReader("content"))
+	assert.NoError(t, err)
+
+	// same permissions as files created by other tools under the umask
+	refDir := filepath.Join(root, "ref")
+	assert.NoError(t, os.Mkdir(refDir, 0755))
+	refFile, err := os.OpenFile(filepath.Join(refDir, "file"), os.O_WRONLY|os.O_CREATE, 0644)
+	assert.NoError(t, err)
+	assert.NoError(t, refFile.Close())
+
+	refDirInfo, err := os.Stat(refDir)
+	assert.NoError(t, err)
+	refFileInfo, err := os.Stat(refFile.Name())
+	assert.NoError(t, err)
+
+	dirInfo, err := os.Stat(filepath.Join(root, "a", "bc", "def"))
+	assert.NoError(t, err)
+	fileInfo, err := os.Stat(filepath.Join(root, "a", "bc", "def", "cache.json"))
+	assert.NoError(t, err)
+	assert.Equal(t, refDirInfo.Mode().Perm(), dirInfo.Mode().Perm())
+	assert.Equal(t, refFileInfo.Mode().Perm(), fileInfo.Mode().Perm())
+
+	// no temp files are left behind
+	names, err := os.ReadDir(filepath.Join(root, "a", "bc", "def"))
+	assert.NoError(t, err)
+	assert.Len(t, names, 1)
+}
+
+func TestIsBlob(t *testing.T) {
+	assert.True(t, IsBlob(strings.Repeat("0f", 32)))
+	assert.False(t, IsBlob("cache.json"))
+	assert.False(t, IsBlob(strings.Repeat("0F", 32)))
+	assert.False(t, IsBlob(strings.Repeat("0f", 31)))
+}
+
+func TestHTTPStorage(t *testing.T) {
+	server := fileServer(t)
+	testStorage(t, NewHTTP(server.URL+"/", time.Second))
+}
+
+func TestHTTPStorageUnreachable(t *testing.T) {
+	s := NewHTTP("http://127.0.0.1:1", time.Second)
+	_, err := s.Exists("a/bc/def", "cache.json")
+	assert.Error(t, err)
+}
+
+func TestNew(t *testing.T) {
+	s, err := New("https://cache.example.com/go", Options{Timeout: time.Second, Token: "secret"})
+	assert.NoError(t, err)
+	assert.IsType(t, &HTTPStorage{}, s)
+	assert.Equal(t, "secret", s.(*HTTPStorage).Token)
+
+	s, err = New("file:///tmp/cache", Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, "/tmp/cache", s.(*DirStorage).Root)
+
+	_, err = New("ftp://cache.example.com", Options{})
+	assert.ErrorContains(t, err, "unsupported storage url scheme")
+}
+
+func TestValidatePath(t *testing.T) {
+	assert.NoError(t, ValidatePath("a/bc/def", "cache.json"))
+	assert.Error(t, ValidatePath("", "cache.json"))
+	assert.Error(t, ValidatePath("a/bc/def", ""))
+	assert.Error(t, ValidatePath("a/../def", "cache.json"))
+	assert.Error(t, ValidatePath("/a/bc", "cache.json"))
+	assert.Error(t, ValidatePath("a/bc/def", "../cache.json"))
+	assert.Error(t, ValidatePath("a/bc/def", ".."))
+}
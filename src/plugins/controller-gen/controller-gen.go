@@ -6,8 +6,8 @@ import (
 	"path"
 	"path/filepath"
 
-	"github.com/oNaiPs/go-generate-fast/src/core/golist"
 	"github.com/oNaiPs/go-generate-fast/src/plugins"
+	"github.com/oNaiPs/go-generate-fast/src/utils/fs"
 	"go.uber.org/zap"
 	"sigs.k8s.io/controller-tools/pkg/crd"
 	"sigs.k8s.io/controller-tools/pkg/deepcopy"
@@ -19,7 +19,7 @@ import (
 )
 
 type ControllerGenPlugin struct {
-	plugins.Plugin
+	plugins.BasePlugin
 }
 
 func (p *ControllerGenPlugin) Name() string {
@@ -117,7 +117,7 @@ func (p *ControllerGenPlugin) ComputeInputOutputFiles(opts plugins.GenerateOpts)
 				ioFiles.InputFiles = append(ioFiles.InputFiles, val.HeaderFile)
 			}
 		case schemapatcher.Generator:
-			dirEntries, err := os.ReadDir(val.ManifestsPath)
+			dirEntries, err := os.ReadDir(fs.ResolvePath(opts.Dir(), val.ManifestsPath))
 			if err != nil {
 				zap.S().Errorw("cannot ready manifests path: %w", err)
 				return nil
@@ -144,7 +144,7 @@ func (p *ControllerGenPlugin) ComputeInputOutputFiles(opts plugins.GenerateOpts)
 		}
 	}
 
-	for _, pkg := range golist.ModulesAndErrors(inputPaths) {
+	for _, pkg := range opts.Loader.GoFiles(opts.Dir(), inputPaths) {
 		if pkg.Error != nil {
 			zap.S().Errorf("cannot get input path: ", pkg.Error)
 			continue
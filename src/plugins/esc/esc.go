@@ -6,17 +6,24 @@ import (
 	"regexp"
 
 	"github.com/oNaiPs/go-generate-fast/src/plugins"
+	"github.com/oNaiPs/go-generate-fast/src/utils/fs"
 	"go.uber.org/zap"
 )
 
 type EscPlugin struct {
-	plugins.Plugin
+	plugins.BasePlugin
 }
 
 func (p *EscPlugin) Name() string {
 	return "esc"
 }
 
+// 2: embedded directories are input dirs, rather than the files listed in
+// them
+func (p *EscPlugin) Version() string {
+	return "2"
+}
+
 func (p *EscPlugin) Matches(opts plugins.GenerateOpts) bool {
 	return opts.ExecutableName == "esc" ||
 		opts.GoPackage == "github.com/mjibson/esc"
@@ -51,45 +58,23 @@ func (p *EscPlugin) ComputeInputOutputFiles(opts plugins.GenerateOpts) *plugins.
 		}
 	}
 
-	directories := []string{}
 	for _, base := range conf.Files {
-
-		files := []string{base}
-		for len(files) > 0 {
-			fname := files[0]
-			files = files[1:]
-			if ignoreRegexp != nil && ignoreRegexp.MatchString(fname) {
-				continue
-			}
-			f, err := os.Open(fname)
-			if err != nil {
-				zap.S().Warn("esc parsing error: %s", err)
-				return nil
-			}
-			fi, err := f.Stat()
-			if err != nil {
-				zap.S().Warn("esc parsing error: %s", err)
-				return nil
-			}
-			fpath := filepath.ToSlash(fname)
-			if fi.IsDir() {
-				fis, err := f.Readdir(0)
-				if err != nil {
-					zap.S().Warn("esc parsing error: %s", err)
-					return nil
-				}
-				for _, fi := range fis {
-					childFName := filepath.Join(fname, fi.Name())
-					files = append(files, childFName)
-					if ignoreRegexp != nil && ignoreRegexp.MatchString(childFName) {
-						continue
-					}
-				}
-				directories = append(directories, fpath)
-			} else if includeRegexp == nil || includeRegexp.MatchString(fname) {
-				escFiles = append(escFiles, fpath)
-			}
-			_ = f.Close()
+		if ignoreRegexp != nil && ignoreRegexp.MatchString(base) {
+			continue
+		}
+		fi, err := os.Stat(fs.ResolvePath(opts.Dir(), base))
+		if err != nil {
+			zap.S().Warn("esc parsing error: %s", err)
+			return nil
+		}
+		if fi.IsDir() {
+			// files skipped by -include and -ignore are inputs too, changing
+			// them only costs a regeneration
+			ioFiles.InputDirs = append(ioFiles.InputDirs, plugins.InputDir{Path: base})
+			continue
+		}
+		if includeRegexp == nil || includeRegexp.MatchString(base) {
+			escFiles = append(escFiles, filepath.ToSlash(base))
 		}
 	}
 
@@ -100,7 +85,6 @@ func (p *EscPlugin) ComputeInputOutputFiles(opts plugins.GenerateOpts) *plugins.
 		fakeOutFileName = conf.OutputFile
 	}
 	ioFiles.OutputFiles = append(ioFiles.OutputFiles, fakeOutFileName)
-	ioFiles.Extra = directories
 
 	return &ioFiles
 }
@@ -100,3 +100,27 @@ func TestEscPlugin_ComputeInputOutputFiles_DefaultOut(t *testing.T) {
 	assert.NotNil(t, ioFiles, "Expected non-nil input/output files")
 	assert.Equal(t, []string{"static.go"}, ioFiles.OutputFiles, "Unexpected output files")
 }
+
+func TestEscPlugin_ComputeInputOutputFiles_Dir(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(path.Join(dir, "static"), 0700))
+	assert.NoError(t, os.WriteFile(path.Join(dir, "static", "index.html"), []byte("index"), 0600))
+	assert.NoError(t, os.WriteFile(path.Join(dir, "main.css"), []byte("css"), 0600))
+
+	opts := plugins.GenerateOpts{
+		Path: path.Join(dir, "gen.go"),
+		SanitizedArgs: []string{
+			"-ignore", "\\.DS_Store",
+			"static",
+			"main.css",
+		},
+	}
+	plugin := &EscPlugin{}
+	ioFiles := plugin.ComputeInputOutputFiles(opts)
+	assert.NotNil(t, ioFiles, "Expected non-nil input/output files")
+	assert.Equal(t, []plugins.InputDir{{Path: "static"}}, ioFiles.InputDirs, "Unexpected input dirs")
+	assert.Equal(t, []string{"main.css"}, ioFiles.InputFiles, "Unexpected input files")
+
+	opts.SanitizedArgs = []string{"missing"}
+	assert.Nil(t, plugin.ComputeInputOutputFiles(opts))
+}
@@ -3,7 +3,7 @@ package plugin_example
 import "github.com/oNaiPs/go-generate-fast/src/plugins"
 
 type ExamplePlugin struct {
-	plugins.Plugin
+	plugins.BasePlugin
 }
 
 func (p *ExamplePlugin) Name() string {
@@ -10,7 +10,7 @@ import (
 )
 
 type GennyPlugin struct {
-	plugins.Plugin
+	plugins.BasePlugin
 }
 
 func (p *GennyPlugin) Name() string {
@@ -7,7 +7,7 @@ import (
 )
 
 type GobindataPlugin struct {
-	plugins.Plugin
+	plugins.BasePlugin
 }
 
 func (p *GobindataPlugin) Name() string {
@@ -32,7 +32,7 @@ func (p *GobindataPlugin) ComputeInputOutputFiles(opts plugins.GenerateOpts) *pl
 	var knownFuncs = make(map[string]int)
 	var visitedPaths = make(map[string]bool)
 	for _, input := range cfg.Input {
-		err := findFiles(input.Path, cfg.Prefix, input.Recursive, &toc, cfg.Ignore, knownFuncs, visitedPaths)
+		err := findFiles(opts.Dir(), input.Path, cfg.Prefix, input.Recursive, &toc, cfg.Ignore, knownFuncs, visitedPaths)
 		if err != nil {
 			zap.S().Error("go-bindata: cannot find files: %s", err)
 			return nil
@@ -12,6 +12,7 @@ import (
 	"unicode"
 
 	"github.com/go-bindata/go-bindata"
+	"github.com/oNaiPs/go-generate-fast/src/utils/fs"
 )
 
 // parseArgs create s a new, filled configuration instance
@@ -107,15 +108,16 @@ func (v ByName) Less(i, j int) bool { return v[i].Name() < v[j].Name() }
 // findFiles recursively finds all the file paths in the given directory tree.
 // They are added to the given map as keys. Values will be safe function names
 // for each file, which will be used when generating the output code.
-func findFiles(dir, prefix string, recursive bool, toc *[]bindata.Asset, ignore []*regexp.Regexp, knownFuncs map[string]int, visitedPaths map[string]bool) error {
+// Relative paths are resolved from root.
+func findFiles(root, dir, prefix string, recursive bool, toc *[]bindata.Asset, ignore []*regexp.Regexp, knownFuncs map[string]int, visitedPaths map[string]bool) error {
 	dirpath := dir
 	if len(prefix) > 0 {
-		dirpath, _ = filepath.Abs(dirpath)
-		prefix, _ = filepath.Abs(prefix)
+		dirpath = fs.ResolvePath(root, dirpath)
+		prefix = fs.ResolvePath(root, prefix)
 		prefix = filepath.ToSlash(prefix)
 	}
 
-	fi, err := os.Stat(dirpath)
+	fi, err := os.Stat(fs.ResolvePath(root, dirpath))
 	if err != nil {
 		return err
 	}
@@ -127,7 +129,7 @@ func findFiles(dir, prefix string, recursive bool, toc *[]bindata.Asset, ignore
 		list = []os.FileInfo{fi}
 	} else {
 		visitedPaths[dirpath] = true
-		fd, err := os.Open(dirpath)
+		fd, err := os.Open(fs.ResolvePath(root, dirpath))
 		if err != nil {
 			return err
 		}
@@ -163,22 +165,20 @@ func findFiles(dir, prefix string, recursive bool, toc *[]bindata.Asset, ignore
 			if recursive {
 				recursivePath := filepath.Join(dir, file.Name())
 				visitedPaths[asset.Path] = true
-				_ = findFiles(recursivePath, prefix, recursive, toc, ignore, knownFuncs, visitedPaths)
+				_ = findFiles(root, recursivePath, prefix, recursive, toc, ignore, knownFuncs, visitedPaths)
 			}
 			continue
 		} else if file.Mode()&os.ModeSymlink == os.ModeSymlink {
 			var linkPath string
-			if linkPath, err = os.Readlink(asset.Path); err != nil {
+			if linkPath, err = os.Readlink(fs.ResolvePath(root, asset.Path)); err != nil {
 				return err
 			}
 			if !filepath.IsAbs(linkPath) {
-				if linkPath, err = filepath.Abs(dirpath + "/" + linkPath); err != nil {
-					return err
-				}
+				linkPath = fs.ResolvePath(root, dirpath+"/"+linkPath)
 			}
 			if _, ok := visitedPaths[linkPath]; !ok {
 				visitedPaths[linkPath] = true
-				_ = findFiles(asset.Path, prefix, recursive, toc, ignore, knownFuncs, visitedPaths)
+				_ = findFiles(root, asset.Path, prefix, recursive, toc, ignore, knownFuncs, visitedPaths)
 			}
 			continue
 		}
@@ -200,7 +200,7 @@ func findFiles(dir, prefix string, recursive bool, toc *[]bindata.Asset, ignore
 		}
 
 		asset.Func = safeFunctionName(asset.Name, knownFuncs)
-		asset.Path, _ = filepath.Abs(asset.Path)
+		asset.Path = fs.ResolvePath(root, asset.Path)
 		*toc = append(*toc, asset)
 	}
 
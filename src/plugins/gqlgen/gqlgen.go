@@ -4,19 +4,20 @@ import (
 	"errors"
 	"flag"
 	"fmt"
-	"io/fs"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
 
 	"github.com/99designs/gqlgen/codegen/config"
+	"github.com/goccy/go-yaml"
 	"github.com/oNaiPs/go-generate-fast/src/plugins"
+	"github.com/oNaiPs/go-generate-fast/src/utils/fs"
 	"go.uber.org/zap"
 )
 
 type GqlgenPlugin struct {
-	plugins.Plugin
+	plugins.BasePlugin
 }
 
 func (p *GqlgenPlugin) Name() string {
@@ -50,7 +51,7 @@ func (p *GqlgenPlugin) ComputeInputOutputFiles(opts plugins.GenerateOpts) *plugi
 		return nil
 	}
 
-	cfg, cfgFile, err := getConfig(flags.Config)
+	cfg, cfgFile, err := getConfig(opts.Dir(), flags.Config)
 	if err != nil {
 		zap.S().Errorf("cannot get gqlgen config: %s", err)
 		return nil
@@ -134,12 +135,7 @@ func filename(schemaFile string, filenameTemplate string) string {
 
 var cfgFilenames = []string{".gqlgen.yml", "gqlgen.yml", "gqlgen.yaml"}
 
-func findCfg() (string, error) {
-	dir, err := os.Getwd()
-	if err != nil {
-		return "", fmt.Errorf("unable to get working dir to findCfg: %w", err)
-	}
-
+func findCfg(dir string) (string, error) {
 	cfg := findCfgInDir(dir)
 
 	for cfg == "" && dir != filepath.Dir(dir) {
@@ -164,27 +160,75 @@ func findCfgInDir(dir string) string {
 	return ""
 }
 
-func getConfig(configFile string) (*config.Config, string, error) {
+// getConfig loads the gqlgen config given on the command line, relative to
+// the command dir, or else the closest one. Like gqlgen, paths in the config
+// are relative to the command dir, or to the dir of the closest config.
+func getConfig(dir string, configFile string) (*config.Config, string, error) {
 	if configFile != "" {
-		cfgFile, err := config.LoadConfig(configFile)
-		return cfgFile, configFile, err
+		configFile = fs.ResolvePath(dir, configFile)
+		cfg, err := loadConfig(configFile, dir)
+		return cfg, configFile, err
 	} else {
-		cfgFile, err := findCfg()
-		if err != nil {
-			return nil, cfgFile, err
-		}
-		err = os.Chdir(filepath.Dir(cfgFile))
+		cfgFile, err := findCfg(dir)
 		if err != nil {
 			return nil, cfgFile, err
 		}
-		cfg, err := config.LoadConfig(cfgFile)
-		if errors.Is(err, fs.ErrNotExist) {
-			cfg, err = config.LoadDefaultConfig()
+		cfg, err := loadConfig(cfgFile, filepath.Dir(cfgFile))
+		if errors.Is(err, os.ErrNotExist) {
+			cfg = config.DefaultConfig()
+			resolveConfigPaths(cfg, filepath.Dir(cfgFile))
+			err = config.CompleteConfig(cfg)
 		}
 		return cfg, cfgFile, err
 	}
 }
 
+// loadConfig reads a gqlgen config like config.LoadConfig, but with its paths
+// resolved against dir rather than the working dir. config.LoadConfig cannot be
+// used here: it completes the config right after decoding it, globbing the
+// schema files against the working dir before their paths can be resolved.
+func loadConfig(cfgFile string, dir string) (*config.Config, error) {
+	f, err := os.Open(cfgFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read config: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	cfg := config.DefaultConfig()
+	err = yaml.NewDecoder(f, yaml.DisallowUnknownField()).Decode(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse config: %w", err)
+	}
+
+	resolveConfigPaths(cfg, dir)
+	err = config.CompleteConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// resolveConfigPaths makes the schema and output paths of a config absolute,
+// so that globbing and checking the config do not depend on the working dir.
+func resolveConfigPaths(cfg *config.Config, dir string) {
+	for i, schemaFile := range cfg.SchemaFilename {
+		cfg.SchemaFilename[i] = fs.ResolvePath(dir, schemaFile)
+	}
+
+	for _, p := range []*string{
+		&cfg.Exec.Filename,
+		&cfg.Exec.DirName,
+		&cfg.Model.Filename,
+		&cfg.Federation.Filename,
+		&cfg.Resolver.Filename,
+		&cfg.Resolver.DirName,
+	} {
+		if *p != "" {
+			*p = fs.ResolvePath(dir, *p)
+		}
+	}
+}
+
 func getOutputSchemaFilenames(cfg *config.Config) ([]string, error) {
 	schemaFiles := make(map[string]bool)
 	if cfg.Schema == nil {
@@ -49,9 +49,6 @@ model:
 	err = os.WriteFile(schemaFile, []byte("type Query {text: String!}"), 0666)
 	assert.NoError(t, err)
 
-	err = os.Chdir(tempDir)
-	assert.NoError(t, err)
-
 	var g GqlgenPlugin
 	option := plugins.GenerateOpts{
 		Path: path.Join(tempDir, "test.go"),
@@ -65,3 +62,39 @@ model:
 
 	assert.NotNil(t, g.ComputeInputOutputFiles(option))
 }
+
+func TestComputeInputOutputFilesConfigInParentDir(t *testing.T) {
+	tempDir := t.TempDir()
+	configContent := `schema:
+  - graph/*.graphql
+exec:
+  filename: graph/generated.go
+model:
+  filename: graph/model/models_gen.go
+`
+	assert.NoError(t, os.WriteFile(path.Join(tempDir, "gqlgen.yml"), []byte(configContent), 0666))
+	assert.NoError(t, os.WriteFile(path.Join(tempDir, "go.mod"), []byte("module example.com/app\n"), 0666))
+	assert.NoError(t, os.MkdirAll(path.Join(tempDir, "graph"), 0777))
+	schemaFile := path.Join(tempDir, "graph", "schema.graphql")
+	assert.NoError(t, os.WriteFile(schemaFile, []byte("type Query {text: String!}"), 0666))
+
+	var g GqlgenPlugin
+	option := plugins.GenerateOpts{
+		Path:          path.Join(tempDir, "graph", "test.go"),
+		SanitizedArgs: []string{"generate"},
+	}
+
+	// paths are relative to the config dir, whatever the working dir
+	ioFiles := g.ComputeInputOutputFiles(option)
+	if assert.NotNil(t, ioFiles) {
+		assert.Equal(t, []string{path.Join(tempDir, "gqlgen.yml"), schemaFile}, ioFiles.InputFiles)
+		assert.Equal(t, []string{
+			path.Join(tempDir, "graph", "model", "models_gen.go"),
+			path.Join(tempDir, "graph", "generated.go"),
+		}, ioFiles.OutputFiles)
+	}
+
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NotEqual(t, tempDir, cwd)
+}
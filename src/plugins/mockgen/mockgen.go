@@ -5,12 +5,11 @@ import (
 	"strings"
 
 	"github.com/oNaiPs/go-generate-fast/src/plugins"
-	"github.com/oNaiPs/go-generate-fast/src/utils/pkg"
 	"go.uber.org/zap"
 )
 
 type MockgenPlugin struct {
-	plugins.Plugin
+	plugins.BasePlugin
 }
 
 func (p *MockgenPlugin) Name() string {
@@ -103,7 +102,7 @@ func (p *MockgenPlugin) ComputeInputOutputFiles(opts plugins.GenerateOpts) *plug
 
 	if len(flagSet.Args()) == 2 {
 
-		pkg := pkg.LoadPackages(opts.Dir(), []string{flagSet.Args()[0]}, []string{})
+		pkg := opts.Loader.Packages(opts.Dir(), []string{flagSet.Args()[0]}, []string{})
 		if pkg == nil {
 			//did not find package matches
 			return nil
@@ -4,12 +4,12 @@ import (
 	"flag"
 
 	"github.com/oNaiPs/go-generate-fast/src/plugins"
-	"github.com/oNaiPs/go-generate-fast/src/utils/pkg"
+	"github.com/oNaiPs/go-generate-fast/src/utils/fs"
 	"go.uber.org/zap"
 )
 
 type MoqPlugin struct {
-	plugins.Plugin
+	plugins.BasePlugin
 }
 
 func (p *MoqPlugin) Name() string {
@@ -66,7 +66,7 @@ func (p *MoqPlugin) ComputeInputOutputFiles(opts plugins.GenerateOpts) *plugins.
 
 	ioFiles := plugins.InputOutputFiles{}
 
-	pkg := pkg.LoadPackages(flags.args[0], []string{}, []string{})
+	pkg := opts.Loader.Packages(fs.ResolvePath(opts.Dir(), flags.args[0]), []string{}, []string{})
 	if pkg == nil {
 		//did not find package matches
 		return nil
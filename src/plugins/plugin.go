@@ -2,14 +2,20 @@ package plugins
 
 import (
 	"path/filepath"
+	"runtime/debug"
 	"strings"
 
+	"github.com/oNaiPs/go-generate-fast/src/core/loader"
 	"go.uber.org/zap"
 )
 
 type GenerateOpts struct {
 	// full path name of the file where the command is being run.
 	Path string
+	// line of the command in the file.
+	Line int
+	// the command as written in the file, before variables are expanded
+	Directive string
 	// all the words being passed on the generate macro
 	Words []string
 	// name of the executable being run.
@@ -24,6 +30,12 @@ type GenerateOpts struct {
 	GoPackage string
 	// when this command is a "go run [pkg]@version" command, the version specified on it (e.g. 1.2.3, latest). Empty string when not specified.
 	GoPackageVersion string
+	// when this command is a "go tool [tool]" command, the tool as given (e.g. stringer, golang.org/x/tools/cmd/stringer)
+	GoTool string
+	// when this command is a "go run" command, the build flags passed to it (e.g. -tags=a, -mod=vendor)
+	GoBuildFlags []string
+	// when this command is a "go run file.go..." command, the go files being run. GoPackage is the first one.
+	GoFiles []string
 	// arguments being passed to the target executable.
 	// examples:
 	// [exec] -a -b arg -> ["-a", "-b", "arg"]
@@ -33,6 +45,11 @@ type GenerateOpts struct {
 	ExtraInputPatterns []string
 	// optionally added output files before the command
 	ExtraOutputPatterns []string
+	// environment variables the command depends on, by name. Their values are
+	// hashed in the cache key
+	Env map[string]string
+	// loads the packages of the run, nil to load them on every call
+	Loader *loader.Loader
 }
 
 // base name of the file containing the command.
@@ -40,7 +57,8 @@ func (g *GenerateOpts) File() string {
 	return filepath.Base(g.Path)
 }
 
-// full dir where the command is being run on. this is the os.Getwd() when the plugins are running
+// full dir where the command is being run on. relative paths, both in the
+// command arguments and in the computed input output files, are relative to it.
 func (g *GenerateOpts) Dir() string {
 	return filepath.Dir(g.Path)
 }
@@ -51,19 +69,72 @@ func (g *GenerateOpts) Command() string {
 
 type Plugin interface {
 	Name() string
+	// Version identifies how the plugin computes the input and output files.
+	// It is part of the cache keys of the commands the plugin matches, so that
+	// upgrading only invalidates the entries of the changed plugins. Bump it
+	// when a change makes ComputeInputOutputFiles return different files,
+	// patterns or extra data for the same command; changes that return the
+	// same ones, like refactors or fixes of crashes, keep it. An empty version
+	// stands for the go-generate-fast version, invalidating the entries on
+	// every upgrade.
+	Version() string
 	Matches(opts GenerateOpts) bool
 	ComputeInputOutputFiles(opts GenerateOpts) *InputOutputFiles
 }
 
+// BasePlugin is embedded by plugins, for the defaults of Plugin methods.
+type BasePlugin struct{}
+
+// Version returns the first version of a plugin. Plugins override it once
+// they bump it.
+func (BasePlugin) Version() string {
+	return "1"
+}
+
 type InputOutputFiles struct {
-	InputFiles  []string
-	OutputFiles []string
+	InputFiles []string
+	// directories whose files are inputs
+	InputDirs []InputDir
+	// input file patterns, the files they match are inputs
+	InputPatterns []string
+	OutputFiles   []string
 	// output file patterns - will be computed after command is run
 	OutputPatterns []string
 	// extra data that is used in the hash calculations
 	Extra []string
 }
 
+// InputDir is a directory whose files are inputs. Adding or removing files
+// changes the cache key, like changing their content does.
+type InputDir struct {
+	// relative to the command dir, or absolute
+	Path string
+	// patterns of the files to include, relative to Path, e.g. **/*.proto.
+	// All files are included when empty.
+	Include []string
+	// patterns of the files and directories to exclude, relative to Path
+	Exclude []string
+}
+
+// Fingerprint returns what identifies a plugin and its version in cache keys.
+func Fingerprint(plugin Plugin) string {
+	version := plugin.Version()
+	if version == "" {
+		version = toolVersion()
+	}
+	return plugin.Name() + "@" + version
+}
+
+// toolVersion returns the version go-generate-fast was installed with, or
+// (devel) when built from a checkout.
+func toolVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok || info.Main.Version == "" {
+		return "(devel)"
+	}
+	return info.Main.Version
+}
+
 var PluginsMap = make(map[string]Plugin)
 
 func RegisterPlugin(plugin Plugin) {
@@ -15,6 +15,10 @@ func (p *TestPlugin) Name() string {
 	return "test"
 }
 
+func (p *TestPlugin) Version() string {
+	return "1"
+}
+
 func (p *TestPlugin) Matches(opts GenerateOpts) bool {
 	return opts.ExecutableName == "test"
 }
@@ -39,6 +43,29 @@ func TestGenerateOptsCommand(t *testing.T) {
 	assert.Equal(t, opts.Command(), "command arg1")
 }
 
+func TestFingerprint(t *testing.T) {
+	assert.Equal(t, "test@1", Fingerprint(&TestPlugin{}))
+
+	// without a version, the one of go-generate-fast
+	assert.Equal(t, "unversioned@"+toolVersion(), Fingerprint(&unversionedPlugin{}))
+	assert.NotEmpty(t, toolVersion())
+
+	// the default one, for plugins that never bumped it
+	assert.Equal(t, "1", BasePlugin{}.Version())
+}
+
+type unversionedPlugin struct {
+	TestPlugin
+}
+
+func (p *unversionedPlugin) Name() string {
+	return "unversioned"
+}
+
+func (p *unversionedPlugin) Version() string {
+	return ""
+}
+
 func TestRegister(t *testing.T) {
 	ClearPlugins()
 
@@ -14,7 +14,7 @@ import (
 )
 
 type ProtocPlugin struct {
-	plugins.Plugin
+	plugins.BasePlugin
 }
 
 func (p *ProtocPlugin) Name() string {
@@ -8,12 +8,11 @@ import (
 
 	"github.com/oNaiPs/go-generate-fast/src/plugins"
 	"github.com/oNaiPs/go-generate-fast/src/utils/fs"
-	"github.com/oNaiPs/go-generate-fast/src/utils/pkg"
 	"go.uber.org/zap"
 )
 
 type StringerPlugin struct {
-	plugins.Plugin
+	plugins.BasePlugin
 }
 
 func (p *StringerPlugin) Name() string {
@@ -68,16 +67,16 @@ func (p *StringerPlugin) ComputeInputOutputFiles(opts plugins.GenerateOpts) *plu
 	var dir string
 	if len(args) == 0 {
 		dir = opts.Dir()
-	} else if len(args) == 1 && fs.IsDir(args[0]) {
-		dir = args[0]
+	} else if len(args) == 1 && fs.IsDir(fs.ResolvePath(opts.Dir(), args[0])) {
+		dir = fs.ResolvePath(opts.Dir(), args[0])
 	} else {
 		if len(tags) != 0 {
 			zap.S().Fatal("-tags option applies only to directories, not when files are specified")
 		}
-		dir = filepath.Dir(args[0])
+		dir = fs.ResolvePath(opts.Dir(), filepath.Dir(args[0]))
 	}
 
-	pkg := pkg.LoadPackages(dir, args, tags)
+	pkg := opts.Loader.Packages(dir, args, tags)
 	if pkg == nil {
 		//did not find package matches
 		return nil
@@ -0,0 +1,68 @@
+// Package compress wraps readers and writers with the codecs supported for
+// cache blobs.
+package compress
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+const (
+	// None stores files as they are.
+	None = ""
+	Gzip = "gzip"
+)
+
+// Validate returns an error when the codec is not supported.
+func Validate(codec string) error {
+	switch codec {
+	case None, Gzip:
+		return nil
+	default:
+		return fmt.Errorf("unsupported compression %q", codec)
+	}
+}
+
+// Extension returns the file name extension of files compressed with the codec.
+func Extension(codec string) string {
+	switch codec {
+	case Gzip:
+		return ".gz"
+	default:
+		return ""
+	}
+}
+
+// NewWriter returns a writer compressing to w. It must be closed to flush the
+// compressed data, which does not close w.
+func NewWriter(w io.Writer, codec string) (io.WriteCloser, error) {
+	switch codec {
+	case None:
+		return nopWriteCloser{w}, nil
+	case Gzip:
+		return gzip.NewWriter(w), nil
+	default:
+		return nil, Validate(codec)
+	}
+}
+
+// NewReader returns a reader decompressing from r.
+func NewReader(r io.Reader, codec string) (io.ReadCloser, error) {
+	switch codec {
+	case None:
+		return io.NopCloser(r), nil
+	case Gzip:
+		return gzip.NewReader(r)
+	default:
+		return nil, Validate(codec)
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
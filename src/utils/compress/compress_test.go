@@ -0,0 +1,53 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundTrip(t *testing.T) {
+	content := strings.Repeat("package main\n", 100)
+
+	for _, codec := range []string{None, Gzip} {
+		t.Run(codec, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := NewWriter(&buf, codec)
+			require.NoError(t, err)
+			_, err = io.WriteString(w, content)
+			require.NoError(t, err)
+			require.NoError(t, w.Close())
+
+			if codec != None {
+				assert.Less(t, buf.Len(), len(content))
+			}
+
+			r, err := NewReader(&buf, codec)
+			require.NoError(t, err)
+			data, err := io.ReadAll(r)
+			require.NoError(t, err)
+			assert.NoError(t, r.Close())
+			assert.Equal(t, content, string(data))
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(None))
+	assert.NoError(t, Validate(Gzip))
+	assert.ErrorContains(t, Validate("lz4"), "unsupported compression")
+
+	_, err := NewWriter(io.Discard, "lz4")
+	assert.Error(t, err)
+	_, err = NewReader(strings.NewReader(""), "lz4")
+	assert.Error(t, err)
+}
+
+func TestExtension(t *testing.T) {
+	assert.Equal(t, "", Extension(None))
+	assert.Equal(t, ".gz", Extension(Gzip))
+}
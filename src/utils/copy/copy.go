@@ -42,19 +42,29 @@ func CopyHashFile(srcName, destName string) (string, error) {
 	}
 	defer func() { _ = destFile.Close() }()
 
-	h, err := blake2b.New256(nil)
+	hash, err := CopyHash(destFile, srcFile)
 	if err != nil {
 		return "", err
 	}
 
-	if _, err := io.Copy(io.MultiWriter(destFile, h), srcFile); err != nil {
+	err = destFile.Sync()
+	if err != nil {
 		return "", err
 	}
 
-	err = destFile.Sync()
+	return hash, nil
+}
+
+// CopyHash copies src to dst, and returns the hash of the copied content.
+func CopyHash(dst io.Writer, src io.Reader) (string, error) {
+	h, err := blake2b.New256(nil)
 	if err != nil {
 		return "", err
 	}
 
+	if _, err := io.Copy(io.MultiWriter(dst, h), src); err != nil {
+		return "", err
+	}
+
 	return fmt.Sprintf("%x", h.Sum(nil)), nil
 }
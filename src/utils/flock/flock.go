@@ -0,0 +1,84 @@
+// Package flock provides advisory file locks, used to coordinate processes
+// sharing a directory.
+package flock
+
+import (
+	"errors"
+	"os"
+)
+
+// ErrLocked is returned by TryAcquire when the lock is held by someone else.
+var ErrLocked = errors.New("file is locked")
+
+type Mode int
+
+const (
+	// Shared locks can be held by several owners at the same time.
+	Shared Mode = iota
+	// Exclusive locks can only be held by a single owner.
+	Exclusive
+)
+
+// Lock is an acquired lock, released with Release.
+// Locks are bound to the open file, so two locks on the same path conflict even
+// when acquired by the same process.
+type Lock struct {
+	file *os.File
+}
+
+// Acquire blocks until the lock on path is acquired. The lock file is created
+// with the given permissions when it does not exist.
+func Acquire(path string, perm os.FileMode, mode Mode) (*Lock, error) {
+	return acquire(path, perm, mode, true)
+}
+
+// TryAcquire acquires the lock on path, or returns ErrLocked without blocking
+// when it is held by someone else.
+func TryAcquire(path string, perm os.FileMode, mode Mode) (*Lock, error) {
+	return acquire(path, perm, mode, false)
+}
+
+func acquire(path string, perm os.FileMode, mode Mode, block bool) (*Lock, error) {
+	file, err := openFile(path, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	err = lockFile(file, mode, block)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return &Lock{file: file}, nil
+}
+
+// openFile opens the lock file, creating it with exactly the given
+// permissions regardless of the umask.
+func openFile(path string, perm os.FileMode) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, perm)
+	if errors.Is(err, os.ErrExist) {
+		return os.OpenFile(path, os.O_RDWR, 0)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = file.Chmod(perm)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// Release releases the lock. The lock file is kept, removing it could let
+// another process lock a file that is no longer reachable by its path.
+func (l *Lock) Release() error {
+	err := unlockFile(l.file)
+	closeErr := l.file.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
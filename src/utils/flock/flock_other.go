@@ -0,0 +1,15 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows)
+
+package flock
+
+import "os"
+
+// file locks are not supported, processes are not coordinated
+
+func lockFile(file *os.File, mode Mode, block bool) error {
+	return nil
+}
+
+func unlockFile(file *os.File) error {
+	return nil
+}
@@ -0,0 +1,80 @@
+package flock
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExclusiveLock(t *testing.T) {
+	lockPath := path.Join(t.TempDir(), "test.lock")
+
+	lock, err := TryAcquire(lockPath, 0600, Exclusive)
+	require.NoError(t, err)
+
+	_, err = TryAcquire(lockPath, 0600, Exclusive)
+	assert.ErrorIs(t, err, ErrLocked)
+	_, err = TryAcquire(lockPath, 0600, Shared)
+	assert.ErrorIs(t, err, ErrLocked)
+
+	assert.NoError(t, lock.Release())
+	assert.FileExists(t, lockPath)
+
+	lock, err = TryAcquire(lockPath, 0600, Exclusive)
+	require.NoError(t, err)
+	assert.NoError(t, lock.Release())
+}
+
+func TestSharedLock(t *testing.T) {
+	lockPath := path.Join(t.TempDir(), "test.lock")
+
+	lock1, err := Acquire(lockPath, 0600, Shared)
+	require.NoError(t, err)
+	lock2, err := TryAcquire(lockPath, 0600, Shared)
+	require.NoError(t, err)
+
+	_, err = TryAcquire(lockPath, 0600, Exclusive)
+	assert.ErrorIs(t, err, ErrLocked)
+
+	assert.NoError(t, lock1.Release())
+	assert.NoError(t, lock2.Release())
+}
+
+func TestAcquireWaits(t *testing.T) {
+	lockPath := path.Join(t.TempDir(), "test.lock")
+
+	lock, err := Acquire(lockPath, 0600, Exclusive)
+	require.NoError(t, err)
+
+	acquired := make(chan *Lock)
+	go func() {
+		lock, err := Acquire(lockPath, 0600, Exclusive)
+		assert.NoError(t, err)
+		acquired <- lock
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("lock acquired while held")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	assert.NoError(t, lock.Release())
+	assert.NoError(t, (<-acquired).Release())
+}
+
+func TestLockFilePerm(t *testing.T) {
+	lockPath := path.Join(t.TempDir(), "test.lock")
+
+	lock, err := Acquire(lockPath, 0660, Shared)
+	require.NoError(t, err)
+	assert.NoError(t, lock.Release())
+
+	info, err := os.Stat(lockPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0660), info.Mode().Perm())
+}
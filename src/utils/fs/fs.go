@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"syscall"
 
 	"go.uber.org/zap"
 )
@@ -37,3 +38,69 @@ func FindExecutablePath(executable string) (string, error) {
 	// resolve executable and use absolute path
 	return exec.LookPath(executable)
 }
+
+// MkdirAll creates a directory along with any necessary parents, like
+// os.MkdirAll, but the created directories get exactly the given permissions
+// regardless of the umask.
+func MkdirAll(path string, perm os.FileMode) error {
+	info, err := os.Stat(path)
+	if err == nil {
+		if !info.IsDir() {
+			return &os.PathError{Op: "mkdir", Path: path, Err: syscall.ENOTDIR}
+		}
+		return nil
+	}
+
+	parent := filepath.Dir(path)
+	if parent != path {
+		err = MkdirAll(parent, perm)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = os.Mkdir(path, perm)
+	if err != nil {
+		// created concurrently by another process
+		if os.IsExist(err) && IsDir(path) {
+			return nil
+		}
+		return err
+	}
+
+	return os.Chmod(path, perm)
+}
+
+// FileStat holds the attributes of a file that change whenever its content
+// is replaced or modified. Times are in nanoseconds since the epoch.
+type FileStat struct {
+	Size       int64
+	ModTime    int64
+	ChangeTime int64
+	Inode      uint64
+}
+
+// ResolvePath returns path as is when absolute, or joined to dir when relative.
+func ResolvePath(dir string, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// FindGoMod returns the go.mod file of the module containing dir, or an empty
+// string outside of a module.
+func FindGoMod(dir string) string {
+	for {
+		goMod := filepath.Join(dir, "go.mod")
+		if _, err := os.Stat(goMod); err == nil {
+			return goMod
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
@@ -2,7 +2,9 @@ package fs
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -59,3 +61,73 @@ func TestFindExecutablePath(t *testing.T) {
 		require.NotEmpty(t, execPath, "Expected path, got empty string.")
 	})
 }
+
+func TestMkdirAll(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "a", "b")
+
+	err := MkdirAll(dir, 0770)
+	require.NoError(t, err)
+
+	for _, d := range []string{dir, filepath.Dir(dir)} {
+		info, err := os.Stat(d)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0770), info.Mode().Perm())
+	}
+
+	// existing dirs are kept as they are
+	err = MkdirAll(dir, 0700)
+	require.NoError(t, err)
+	info, err := os.Stat(dir)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0770), info.Mode().Perm())
+
+	file := filepath.Join(dir, "file")
+	require.NoError(t, os.WriteFile(file, []byte{}, 0600))
+	assert.Error(t, MkdirAll(file, 0700))
+}
+
+func TestTouch(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(file, []byte{}, 0600))
+
+	old := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(file, old, old))
+
+	require.NoError(t, Touch(file))
+
+	info, err := os.Stat(file)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), info.ModTime(), time.Minute)
+}
+
+func TestStat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(path, []byte("content"), 0600))
+
+	modTime := time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+
+	stat, err := Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), stat.Size)
+	assert.Equal(t, modTime.UnixNano(), stat.ModTime)
+
+	_, err = Stat(filepath.Join(t.TempDir(), "missing"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestResolvePath(t *testing.T) {
+	dir := filepath.Join(string(filepath.Separator)+"base", "dir")
+	assert.Equal(t, filepath.Join(dir, "file"), ResolvePath(dir, "file"))
+	assert.Equal(t, filepath.Join(filepath.Dir(dir), "file"), ResolvePath(dir, "../file"))
+	assert.Equal(t, filepath.Join(string(filepath.Separator)+"abs", "file"), ResolvePath(dir, filepath.Join(string(filepath.Separator)+"abs", "file")))
+}
+
+func TestFindGoMod(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "a", "b"), 0700))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/mod\n"), 0600))
+
+	assert.Equal(t, filepath.Join(dir, "go.mod"), FindGoMod(dir))
+	assert.Equal(t, filepath.Join(dir, "go.mod"), FindGoMod(filepath.Join(dir, "a", "b")))
+}
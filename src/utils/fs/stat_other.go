@@ -0,0 +1,19 @@
+//go:build !unix
+
+package fs
+
+import "os"
+
+// Stat returns the identity attributes of a file, following symlinks. The
+// change time and inode are not available on this platform, and left zero.
+func Stat(path string) (FileStat, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return FileStat{}, err
+	}
+
+	return FileStat{
+		Size:    info.Size(),
+		ModTime: info.ModTime().UnixNano(),
+	}, nil
+}
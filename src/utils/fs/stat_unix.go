@@ -0,0 +1,25 @@
+//go:build unix
+
+package fs
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// Stat returns the identity attributes of a file, following symlinks.
+func Stat(path string) (FileStat, error) {
+	var st unix.Stat_t
+	err := unix.Stat(path, &st)
+	if err != nil {
+		return FileStat{}, &os.PathError{Op: "stat", Path: path, Err: err}
+	}
+
+	return FileStat{
+		Size:       st.Size,
+		ModTime:    st.Mtim.Nano(),
+		ChangeTime: st.Ctim.Nano(),
+		Inode:      uint64(st.Ino),
+	}, nil
+}
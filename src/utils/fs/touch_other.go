@@ -0,0 +1,14 @@
+//go:build !unix
+
+package fs
+
+import (
+	"os"
+	"time"
+)
+
+// Touch sets the access and modification times of a file to the current time.
+func Touch(path string) error {
+	now := time.Now()
+	return os.Chtimes(path, now, now)
+}
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"path/filepath"
 	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // StringList flattens its arguments into a single []string.
@@ -53,3 +56,56 @@ func ConvertToRelativePaths(elements *[]string, basepath string) error {
 	}
 	return nil
 }
+
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"TB", 1 << 40},
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// ParseSize parses a size in bytes with an optional unit, e.g. "512", "100MB" or "1.5gb".
+func ParseSize(s string) (int64, error) {
+	trimmed := strings.ToUpper(strings.TrimSpace(s))
+	multiplier := int64(1)
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(trimmed, unit.suffix) {
+			trimmed = strings.TrimSpace(strings.TrimSuffix(trimmed, unit.suffix))
+			multiplier = unit.bytes
+			break
+		}
+	}
+
+	value, err := strconv.ParseFloat(trimmed, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(value * float64(multiplier)), nil
+}
+
+// FormatSize formats a size in bytes with the largest unit that fits, e.g. "1.5MB".
+func FormatSize(size int64) string {
+	for _, unit := range sizeUnits {
+		if size >= unit.bytes && unit.bytes > 1 {
+			return strconv.FormatFloat(float64(size)/float64(unit.bytes), 'f', 1, 64) + unit.suffix
+		}
+	}
+	return strconv.FormatInt(size, 10) + "B"
+}
+
+// ParseDuration is like time.ParseDuration, but also accepts a number of days, e.g. "30d".
+func ParseDuration(s string) (time.Duration, error) {
+	trimmed := strings.TrimSpace(s)
+	if days, found := strings.CutSuffix(trimmed, "d"); found {
+		value, err := strconv.ParseFloat(days, 64)
+		if err != nil || value < 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(value * float64(24*time.Hour)), nil
+	}
+	return time.ParseDuration(trimmed)
+}
@@ -2,6 +2,7 @@ package str
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -87,3 +88,47 @@ func TestConvertToRelativePaths(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, []string{"file1.txt", "rel2/file2.txt", "rel3/rel3/file3.txt"}, elements)
 }
+
+func TestParseSize(t *testing.T) {
+	for input, expected := range map[string]int64{
+		"0":      0,
+		"512":    512,
+		"512B":   512,
+		"2KB":    2048,
+		"100MB":  100 << 20,
+		"1.5gb":  3 << 29,
+		" 1 TB ": 1 << 40,
+	} {
+		size, err := ParseSize(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, expected, size, input)
+	}
+
+	_, err := ParseSize("lots")
+	assert.ErrorContains(t, err, "invalid size \"lots\"")
+	_, err = ParseSize("-1MB")
+	assert.Error(t, err)
+}
+
+func TestFormatSize(t *testing.T) {
+	assert.Equal(t, "0B", FormatSize(0))
+	assert.Equal(t, "1023B", FormatSize(1023))
+	assert.Equal(t, "1.0KB", FormatSize(1024))
+	assert.Equal(t, "1.5MB", FormatSize(3<<19))
+	assert.Equal(t, "2.0GB", FormatSize(2<<30))
+}
+
+func TestParseDuration(t *testing.T) {
+	d, err := ParseDuration("30d")
+	assert.NoError(t, err)
+	assert.Equal(t, 30*24*time.Hour, d)
+
+	d, err = ParseDuration("1h30m")
+	assert.NoError(t, err)
+	assert.Equal(t, 90*time.Minute, d)
+
+	_, err = ParseDuration("xd")
+	assert.Error(t, err)
+	_, err = ParseDuration("soon")
+	assert.Error(t, err)
+}